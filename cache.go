@@ -0,0 +1,161 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SetCacheDir sets the directory to cache remote sources. Every successfully
+// fetched remote object is persisted in the directory. If a remote source can
+// not be fetched before any success, its cached copy is loaded instead and the
+// source is marked as cached in its status until a fetch succeeds.
+//
+// An empty directory (default) disables the cache.
+func (c *Config) SetCacheDir(dir string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.cacheDir = dir
+}
+
+// getCachePrefix returns the prefix of the cache file of the source, or an
+// empty string if the cache is disabled.
+func (c *Config) getCachePrefix(source string) string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.cacheDir == "" {
+		return ""
+	}
+
+	var hash = sha256.Sum256([]byte(source))
+	return filepath.Join(c.cacheDir, hex.EncodeToString(hash[:8])+"-")
+}
+
+// saveCache persists the data of the source in the cache directory. The name
+// of the cache file always ends with the extension of the format.
+func (c *Config) saveCache(source string, format Format, data []byte) {
+	var prefix = c.getCachePrefix(source)
+	if prefix == "" {
+		return
+	}
+
+	var filename = prefix + path.Base(source)
+	if getFormat(filename) != format {
+		filename += formatExt(format)
+	}
+
+	var err = os.MkdirAll(filepath.Dir(filename), 0700)
+	if err == nil {
+		err = writeFileAtomic(filename, data)
+	}
+
+	if err != nil {
+		logger.Event("cache-error").
+			Field("source", source).Field("error", err).Warning()
+	}
+}
+
+// loadCache reads the cached copy of the source if the source has never been
+// fetched successfully. It returns true if the cached copy is loaded.
+func (c *Config) loadCache(source string, priority int) bool {
+	var prefix = c.getCachePrefix(source)
+	if prefix == "" {
+		return false
+	}
+
+	c.lock.RLock()
+	var status = c.statuses[source]
+	c.lock.RUnlock()
+
+	if !status.LastSuccess.IsZero() || status.Cached {
+		return false
+	}
+
+	var filename = findCacheFile(prefix)
+	if filename == "" {
+		return false
+	}
+
+	var data, err = ioutil.ReadFile(filename)
+	if err == nil {
+		err = c.readBytes(getFormat(filename), priority, data, source)
+	}
+
+	if err != nil {
+		logger.Event("cache-error").
+			Field("source", source).Field("error", err).Warning()
+		return false
+	}
+
+	c.lock.WLockFunc(func() {
+		var status = c.statuses[source]
+		status.Cached = true
+		c.statuses[source] = status
+	})
+
+	logger.Event("load-cache").
+		Field("source", source).Field("filename", filename).Warning()
+	return true
+}
+
+// findCacheFile returns the latest modified cache file with the prefix, or an
+// empty string if there is no such file.
+func findCacheFile(prefix string) string {
+	var entries, err = ioutil.ReadDir(filepath.Dir(prefix))
+	if err != nil {
+		return ""
+	}
+
+	var filename string
+	var modTime time.Time
+	for _, entry := range entries {
+		var name = filepath.Join(filepath.Dir(prefix), entry.Name())
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) ||
+			getFormat(name) == UnknownFormat {
+			continue
+		}
+
+		if filename == "" || entry.ModTime().After(modTime) {
+			filename = name
+			modTime = entry.ModTime()
+		}
+	}
+
+	return filename
+}
+
+// formatExt returns the extension of the format.
+func formatExt(format Format) string {
+	for ext, f := range extensions {
+		if f == format {
+			return ext
+		}
+	}
+	return ""
+}
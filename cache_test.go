@@ -0,0 +1,98 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+func TestConfigCacheDir(t *testing.T) {
+	var dir = t.TempDir()
+	var client = newFakeS3()
+	client.put("bucket", "app.json", `{"foo": "bar"}`, `"1"`)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetCacheDir(dir)
+	cfg.SetS3Client(client)
+	xycond.ExpectNil(cfg.ReadS3("s3://bucket/app.json", 0)).Test(t)
+
+	// Another process starts when S3 is unreachable.
+	var other = xyconfig.GetConfig(t.Name() + "-other")
+	other.SetCacheDir(dir)
+	other.SetS3Client(newFakeS3())
+	xycond.ExpectNil(other.ReadS3("s3://bucket/app.json", 0)).Test(t)
+	xycond.ExpectEqual(other.MustGet("foo").MustString(), "bar").Test(t)
+
+	var status, _ = other.Status("s3://bucket/app.json")
+	xycond.ExpectTrue(status.Cached).Test(t)
+	xycond.ExpectEqual(status.ConsecutiveFailures, 1).Test(t)
+}
+
+func TestConfigCacheDirReplaced(t *testing.T) {
+	var dir = t.TempDir()
+	var client = newFakeS3()
+	client.put("bucket", "app.json", `{"foo": "bar"}`, `"1"`)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetCacheDir(dir)
+	cfg.SetS3Client(client)
+	xycond.ExpectNil(cfg.ReadS3("s3://bucket/app.json", 0)).Test(t)
+
+	var unreachable = newFakeS3()
+	var other = xyconfig.GetConfig(t.Name() + "-other")
+	defer other.CloseWatcher()
+	other.SetCacheDir(dir)
+	other.SetS3Client(unreachable)
+	xycond.ExpectNil(other.ReadS3("s3://bucket/app.json", 10*time.Millisecond)).Test(t)
+	xycond.ExpectEqual(other.MustGet("foo").MustString(), "bar").Test(t)
+
+	unreachable.put("bucket", "app.json", `{"foo": "buzz"}`, `"2"`)
+	xycond.ExpectTrue(eventually(func() bool {
+		var status, _ = other.Status("s3://bucket/app.json")
+		return !status.Cached && other.MustGet("foo").MustString() == "buzz"
+	})).Test(t)
+}
+
+func TestConfigCacheDirNotFound(t *testing.T) {
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetCacheDir(t.TempDir())
+	cfg.SetS3Client(newFakeS3())
+
+	var err = cfg.ReadS3("s3://bucket/app.json", 0)
+	xycond.ExpectError(err, xyconfig.ConfigError).Test(t)
+}
+
+func TestConfigCacheDirDisabled(t *testing.T) {
+	var client = newFakeS3()
+	client.put("bucket", "app.json", `{"foo": "bar"}`, `"1"`)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetS3Client(client)
+	xycond.ExpectNil(cfg.ReadS3("s3://bucket/app.json", 0)).Test(t)
+
+	var other = xyconfig.GetConfig(t.Name() + "-other")
+	other.SetS3Client(newFakeS3())
+	xycond.ExpectNotNil(other.ReadS3("s3://bucket/app.json", 0)).Test(t)
+}
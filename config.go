@@ -21,20 +21,22 @@
 package xyconfig
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/fsnotify/fsnotify"
 	"github.com/go-ini/ini"
 	"github.com/joho/godotenv"
@@ -56,6 +58,10 @@ const (
 
 const maxPriority = 100
 
+// defaultWatchInterval is the default time interval to watch changes of
+// remote instances.
+const defaultWatchInterval = 5 * time.Minute
+
 var loggerName = "xybor.xyplatform.xyconfig"
 var logger = xylog.GetLogger(loggerName)
 
@@ -95,17 +101,112 @@ type Config struct {
 	// watcher tracks changes of files.
 	watcher *fsnotify.Watcher
 
+	// watchedFiles maps cleaned paths of files being watched to the Kubernetes
+	// ConfigMap volumes containing them (or empty). The watcher watches their
+	// parent directories, so the watching survives when a file is replaced by
+	// renaming.
+	watchedFiles map[string]string
+
+	// watchedDirs maps directories being watched by ReadDir to their patterns
+	// and options.
+	watchedDirs map[string]watchedDir
+
 	// timerWatchers tracks the waching of non-inotify instances.
-	timerWatchers map[string]*time.Timer
+	timerWatchers map[string]stopper
 
 	// watchInterval is used to choose the time interval to watch changes when
 	// using Read method.
 	watchInterval time.Duration
 
+	// pollInterval is the time interval to poll files on filesystems which do
+	// not support inotify.
+	pollInterval time.Duration
+
+	// keyProvider provides the key to decrypt encrypted values.
+	keyProvider KeyProvider
+
+	// override is the file persisting changes made by Set.
+	override *overrideFile
+
+	// fileHashes contains the content hashes of files which were last read or
+	// written by Config. The watcher does not reload unchanged files.
+	fileHashes map[string][sha256.Size]byte
+
+	// writtenHashes contains the content hashes of the latest writes of files
+	// by Config, whose events may not be handled by the watcher yet.
+	writtenHashes map[string][][sha256.Size]byte
+
+	// layers contains the values of each key without dot by their sources,
+	// including values overridden by higher priorities. They are restored when
+	// the source of the current value is removed.
+	layers map[string]map[string]Value
+
+	// includedBy contains the files including each file.
+	includedBy map[string]map[string]bool
+
+	// filePriorities contains the priorities which files were read with. Files
+	// included by other files may inherit the priority of the including file.
+	filePriorities map[string]int
+
+	// formats contains the formats which are given explicitly to sources by
+	// WithFormat. They take precedence over extensions.
+	formats map[string]Format
+
+	// debounce is the time window to coalesce changes of a file before it is
+	// reloaded.
+	debounce time.Duration
+
+	// reloadTimers contains the pending reloads of files.
+	reloadTimers map[string]*time.Timer
+
+	// profile is the active profile used by ReadProfile.
+	profile string
+
+	// cacheDir is the directory to cache remote sources.
+	cacheDir string
+
+	// s3Client is the client to read S3 objects. If it is nil, a client is
+	// created from the shared aws config.
+	s3Client s3iface.S3API
+
+	// statuses contains the health status of remote sources.
+	statuses map[string]SourceStatus
+
+	// errorHandler is called when fetching a remote source fails.
+	errorHandler func(source string, err error)
+
+	// httpClient is the client to request HTTP sources.
+	httpClient *http.Client
+
+	// httpHeader contains the headers added to requests of HTTP sources.
+	httpHeader http.Header
+
+	// sqlDialect is the dialect of the queries of SQL sources.
+	sqlDialect SQLDialect
+
+	// versions contains the versions of remote objects which were last read.
+	// Unchanged objects are not downloaded again.
+	versions map[string]remoteVersion
+
+	// subscribers contains the functions called for every change, they are
+	// guarded by subscriberLock.
+	subscribers map[int]func(Event)
+
+	// nextSubscriber is the id of the last subscriber.
+	nextSubscriber int
+
+	// version is the number of changes, it is guarded by subscriberLock.
+	version uint64
+
 	// lock avoids race condition.
 	lock *xylock.RWLock
 }
 
+// stopper stops watching an instance, such as a *time.Timer.
+type stopper interface {
+	Stop() bool
+}
+
 // globalLock avoids race condition of configMap
 var globalLock = &xylock.RWLock{}
 
@@ -136,11 +237,24 @@ func GetConfig(name string) *Config {
 	}
 
 	var cfg = &Config{
-		config:        make(map[string]Value),
-		hook:          make(map[string]func(Event)),
-		timerWatchers: make(map[string]*time.Timer),
-		watchInterval: 5 * time.Minute,
-		lock:          &xylock.RWLock{},
+		config:         make(map[string]Value),
+		hook:           make(map[string]func(Event)),
+		timerWatchers:  make(map[string]stopper),
+		watchedFiles:   make(map[string]string),
+		watchedDirs:    make(map[string]watchedDir),
+		fileHashes:     make(map[string][sha256.Size]byte),
+		writtenHashes:  make(map[string][][sha256.Size]byte),
+		reloadTimers:   make(map[string]*time.Timer),
+		layers:         make(map[string]map[string]Value),
+		includedBy:     make(map[string]map[string]bool),
+		filePriorities: make(map[string]int),
+		formats:        make(map[string]Format),
+		versions:       make(map[string]remoteVersion),
+		statuses:       make(map[string]SourceStatus),
+		watchInterval:  defaultWatchInterval,
+		pollInterval:   5 * time.Second,
+		sqlDialect:     MySQLDialect,
+		lock:           &xylock.RWLock{},
 	}
 
 	if name == "" {
@@ -154,6 +268,11 @@ func GetConfig(name string) *Config {
 	return cfg
 }
 
+// Name returns the name of the Config.
+func (c *Config) Name() string {
+	return c.name
+}
+
 // CloseWatcher closes the watcher.
 func (c *Config) CloseWatcher() error {
 	c.lock.Lock()
@@ -165,6 +284,19 @@ func (c *Config) CloseWatcher() error {
 		c.watcher = nil
 	}
 
+	for k := range c.watchedFiles {
+		delete(c.watchedFiles, k)
+	}
+
+	for k := range c.watchedDirs {
+		delete(c.watchedDirs, k)
+	}
+
+	for k, t := range c.reloadTimers {
+		t.Stop()
+		delete(c.reloadTimers, k)
+	}
+
 	for k, w := range c.timerWatchers {
 		w.Stop()
 		delete(c.timerWatchers, k)
@@ -182,8 +314,9 @@ func (c *Config) SetWatchInterval(d time.Duration) {
 }
 
 // UnWatch removes a filename from the watcher. This method also works with s3
-// url. Put "env" as parameter if you want to stop watching environment
-// variables of LoadEnv().
+// and http urls, SSE streams, key-value sources ("kv:" followed by the prefix)
+// and directories of ReadDir. Put "env" as parameter if you want to stop
+// watching environment variables of LoadEnv().
 func (c *Config) UnWatch(filename string) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
@@ -194,9 +327,32 @@ func (c *Config) UnWatch(filename string) error {
 		return nil
 	}
 
-	if c.watcher != nil {
-		if err := c.watcher.Remove(filename); err != nil {
-			return ConfigError.New(err)
+	if c.watcher == nil {
+		return nil
+	}
+
+	var name = filepath.Clean(filename)
+	if _, ok := c.watchedDirs[name]; ok {
+		return c.unwatchDir(name)
+	}
+
+	var volume, ok = c.watchedFiles[name]
+	if !ok {
+		return ConfigError.Newf("%s is not being watched", filename)
+	}
+	delete(c.watchedFiles, name)
+
+	if timer, ok := c.reloadTimers[name]; ok {
+		timer.Stop()
+		delete(c.reloadTimers, name)
+	}
+
+	// Stop watching directories if no other file in them is watched.
+	for _, dir := range []string{filepath.Dir(name), volume} {
+		if dir != "" && !c.isDirWatched(dir) {
+			if err := c.watcher.Remove(dir); err != nil {
+				return ConfigError.New(err)
+			}
 		}
 	}
 
@@ -207,11 +363,27 @@ func (c *Config) UnWatch(filename string) error {
 // create a new one, otherwise, it overrides the current value.
 //
 // The return value says if a hook function is executed for this change.
+//
+// If an override file is set, the change is also persisted to that file.
 func (c *Config) Set(key string, value any, priority int, strict bool) bool {
+	var hooked = c.set(key, Value{value: value, priority: priority, strict: strict})
+	c.persistOverride(key, value, priority)
+	return hooked
+}
+
+// set assigns the value to key without persisting the change. If the value is
+// unchanged, only its priority and source are updated and no hook is executed.
+func (c *Config) set(key string, value Value) bool {
+	if d, ok := value.value.(decrypted); ok {
+		value.value, value.ciphertext = d.plain, d.cipher
+	}
+
 	var old, ok = c.Get(key)
-	if ok && (old.value == value || old.priority > priority) {
+	if ok && old.priority > value.priority {
+		c.shadow(key, value)
 		return false
 	}
+	var changed = !ok || !isEqual(old.value, value.value)
 
 	c.lock.Lock()
 	defer c.lock.Unlock()
@@ -219,34 +391,69 @@ func (c *Config) Set(key string, value any, priority int, strict bool) bool {
 	var before, after, found = strings.Cut(key, ".")
 	var watched = false
 	if !found {
-		c.config[key] = Value{value: value, priority: priority, strict: strict}
+		c.config[key] = value
+		c.addLayer(key, value)
+		if _, ok := value.AsConfig(); changed && !ok {
+			c.notify(Event{Old: old, New: value, Key: c.name + "." + key})
+		}
 	} else {
 		if _, ok := c.config[before]; !ok {
-			c.config[before] = Value{value: GetConfig(c.name + "." + before), strict: strict}
+			c.config[before] = Value{value: GetConfig(c.name + "." + before), strict: value.strict}
 		}
 
 		if _, ok := c.config[before].AsConfig(); !ok {
-			c.config[before] = Value{value: GetConfig(c.name + "." + before), strict: strict}
+			c.config[before] = Value{value: GetConfig(c.name + "." + before), strict: value.strict}
 		}
 
-		watched = c.config[before].MustConfig().Set(after, value, priority, strict)
+		watched = c.config[before].MustConfig().set(after, value)
 	}
 
-	if !watched {
-		// Find the matched hook with the most detailed key.
-		var prefix string
-		var hook func(Event)
-		for k, v := range c.hook {
-			if k == "" || key == k || strings.HasPrefix(key, k+".") {
-				if k == "" || len(k) > len(prefix) {
-					prefix = k
-					hook = v
-				}
-			}
+	if changed && !watched {
+		if hook := c.findHook(key); hook != nil {
+			value.sensitive = isSensitive(c.name + "." + key)
+			hook(Event{Old: old, New: value, Key: c.name + "." + key})
+			return true
+		}
+	}
+
+	return false
+}
+
+// unset removes the value of the key read from the source. If it is the
+// current value, the value of the key with the next highest priority is
+// restored. The return value says if a hook function is executed for this
+// change.
+func (c *Config) unset(key string, source string) bool {
+	var old, ok = c.Get(key)
+	if !ok || old.source != source {
+		c.dropLayer(key, source)
+		return false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var before, after, found = strings.Cut(key, ".")
+	var watched = false
+	var next Value
+	if !found {
+		c.deleteLayer(key, source)
+		if top, ok := c.topLayer(key); ok {
+			next = top
+			c.config[key] = next
+		} else {
+			delete(c.config, key)
 		}
+		c.notify(Event{Old: old, New: next, Key: c.name + "." + key})
+	} else if sub, ok := c.config[before].AsConfig(); ok {
+		watched = sub.unset(after, source)
+		next, _ = sub.Get(after)
+	}
 
-		if hook != nil {
-			hook(Event{Old: old, New: Value{value: value, strict: strict}, Key: c.name + "." + key})
+	if !watched {
+		if hook := c.findHook(key); hook != nil {
+			next.sensitive = isSensitive(c.name + "." + key)
+			hook(Event{Old: old, New: next, Key: c.name + "." + key})
 			return true
 		}
 	}
@@ -254,6 +461,138 @@ func (c *Config) Set(key string, value any, priority int, strict bool) bool {
 	return false
 }
 
+// removeSource removes all values read from the source. Values of the same
+// keys from other sources are restored.
+func (c *Config) removeSource(source string) {
+	for _, key := range c.keysOf(source) {
+		c.unset(key, source)
+	}
+}
+
+// keysOf returns all keys which have values read from the source, including
+// values overridden by higher priorities.
+func (c *Config) keysOf(source string) []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var keys []string
+	for k, v := range c.config {
+		if sub, ok := v.AsConfig(); ok {
+			for _, subkey := range sub.keysOf(source) {
+				keys = append(keys, k+"."+subkey)
+			}
+		}
+	}
+
+	for k, values := range c.layers {
+		if _, ok := values[source]; ok {
+			keys = append(keys, k)
+		}
+	}
+
+	return keys
+}
+
+// leafOf returns the sub-Config which contains the last part of the key, and
+// that part. The latter returned value is false if the sub-Config does not
+// exist.
+func (c *Config) leafOf(key string) (*Config, string, bool) {
+	var before, after, found = strings.Cut(key, ".")
+	if !found {
+		return c, key, true
+	}
+
+	c.lock.RLock()
+	var v = c.config[before]
+	c.lock.RUnlock()
+
+	if sub, ok := v.AsConfig(); ok {
+		return sub.leafOf(after)
+	}
+	return nil, "", false
+}
+
+// shadow records a value which is overridden by a value with higher priority,
+// so it is restored if the source of that value is removed.
+func (c *Config) shadow(key string, value Value) {
+	var leaf, name, ok = c.leafOf(key)
+	if !ok {
+		return
+	}
+
+	leaf.lock.Lock()
+	defer leaf.lock.Unlock()
+	if leaf.config[name].source != value.source {
+		leaf.addLayer(name, value)
+	}
+}
+
+// dropLayer removes the value of the key read from the source, which is not
+// the current value.
+func (c *Config) dropLayer(key string, source string) {
+	var leaf, name, ok = c.leafOf(key)
+	if !ok {
+		return
+	}
+
+	leaf.lock.WLockFunc(func() {
+		leaf.deleteLayer(name, source)
+	})
+}
+
+// addLayer records the value of a key without dot by its source. The caller
+// must hold the lock.
+func (c *Config) addLayer(key string, value Value) {
+	if _, ok := value.AsConfig(); ok {
+		return
+	}
+
+	if c.layers[key] == nil {
+		c.layers[key] = make(map[string]Value)
+	}
+	c.layers[key][value.source] = value
+}
+
+// deleteLayer removes the value of a key without dot read from the source. The
+// caller must hold the lock.
+func (c *Config) deleteLayer(key string, source string) {
+	delete(c.layers[key], source)
+	if len(c.layers[key]) == 0 {
+		delete(c.layers, key)
+	}
+}
+
+// topLayer returns the recorded value of a key without dot which has the
+// highest priority. Values with the same priority are chosen by the order of
+// their sources. The caller must hold the lock.
+func (c *Config) topLayer(key string) (Value, bool) {
+	var top Value
+	var found = false
+	for source, v := range c.layers[key] {
+		if !found || v.priority > top.priority ||
+			(v.priority == top.priority && source > top.source) {
+			top, found = v, true
+		}
+	}
+	return top, found
+}
+
+// findHook finds the matched hook with the most detailed key.
+func (c *Config) findHook(key string) func(Event) {
+	var prefix string
+	var hook func(Event)
+	for k, v := range c.hook {
+		if k == "" || key == k || strings.HasPrefix(key, k+".") {
+			if k == "" || len(k) > len(prefix) {
+				prefix = k
+				hook = v
+			}
+		}
+	}
+
+	return hook
+}
+
 // AddHook adds a hook function. This function will be executed when there is
 // any change for values of the key.
 //
@@ -290,17 +629,30 @@ func (c *Config) AddHook(key string, f func(e Event)) {
 
 // ReadMap reads the config values from a map. If strict is false and the values
 // of map are strings, it allows casting them to other types.
+//
+// Encrypted string values are decrypted with the KeyProvider of Config.
 func (c *Config) ReadMap(priority int, m map[string]any) error {
+	var decrypted, err = c.newDecrypter().decrypt(m)
+	if err != nil {
+		return err
+	}
+
+	return c.readMap(priority, decrypted.(map[string]any), "")
+}
+
+// readMap reads the config values from a map whose values were decrypted. The
+// values are marked as read from the source.
+func (c *Config) readMap(priority int, m map[string]any, source string) error {
 	for k, v := range m {
 		switch t := v.(type) {
 		case map[string]any:
 			var cfg = GetConfig(c.name + "." + k)
-			if err := cfg.ReadMap(priority, t); err != nil {
+			if err := cfg.readMap(priority, t, source); err != nil {
 				return err
 			}
-			c.Set(k, cfg, priority, true)
+			c.set(k, Value{value: cfg, priority: priority, strict: true, source: source})
 		default:
-			c.Set(k, t, priority, true)
+			c.set(k, Value{value: t, priority: priority, strict: true, source: source})
 		}
 	}
 
@@ -309,54 +661,149 @@ func (c *Config) ReadMap(priority int, m map[string]any) error {
 
 // ReadJSON reads the config values from a byte array under JSON format.
 func (c *Config) ReadJSON(priority int, b []byte) error {
+	return c.readJSON(priority, b, "")
+}
+
+// readJSON reads the config values from a byte array under JSON format. The
+// values are marked as read from the source.
+func (c *Config) readJSON(priority int, b []byte, source string) error {
 	var m map[string]any
 	var err = json.Unmarshal(b, &m)
 	if err != nil {
-		return xyerror.ValueError.Newf("cannot parse json data (%v)", err)
+		return parseError(JSON, b, err)
 	}
+	delete(m, jsonIncludeKey)
 
-	return c.ReadMap(priority, m)
+	decrypted, err := c.newDecrypter().decrypt(m)
+	if err != nil {
+		return err
+	}
+
+	return c.readMap(priority, decrypted.(map[string]any), source)
+}
+
+// parseError summarizes an error of parsing data under the format. Parsers may
+// quote the offending line, which can contain a secret, so the error only
+// contains the line number.
+func parseError(format Format, data []byte, err error) error {
+	var name, line = "", 0
+	switch format {
+	case JSON:
+		name = "json"
+		var syntaxErr *json.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			return xyerror.ValueError.Newf("cannot parse json data (%v)", err)
+		}
+		if syntaxErr.Offset <= int64(len(data)) {
+			line = bytes.Count(data[:syntaxErr.Offset], []byte("\n")) + 1
+		}
+	case INI:
+		name = "ini"
+		// Errors of go-ini end with the offending line.
+		var _, quoted, _ = strings.Cut(err.Error(), ": ")
+		quoted = strings.TrimSpace(quoted)
+		for i, l := range strings.Split(string(data), "\n") {
+			if quoted != "" && strings.Contains(l, quoted) {
+				line = i + 1
+				break
+			}
+		}
+	}
+
+	if line == 0 {
+		return xyerror.ValueError.Newf("cannot parse %s data", name)
+	}
+	return xyerror.ValueError.Newf("cannot parse %s data at line %d", name, line)
 }
 
 // ReadINI reads the config values from a byte array under INI format.
 func (c *Config) ReadINI(priority int, b []byte) error {
+	return c.readINI(priority, b, "")
+}
+
+// readINI reads the config values from a byte array under INI format. The
+// values are marked as read from the source.
+func (c *Config) readINI(priority int, b []byte, source string) error {
 	var cfg, err = ini.Load(b)
 	if err != nil {
-		return xyerror.ValueError.New(err)
+		return parseError(INI, b, err)
 	}
 
+	// Decrypt all values before setting, so a failure will not leave the
+	// Config partially updated.
+	var d = c.newDecrypter()
+	var values = make(map[string]any)
 	for _, section := range cfg.Sections() {
 		for _, key := range section.Keys() {
-			c.Set(section.Name()+"."+key.Name(), key.Value(), priority, false)
+			if section.Name() == ini.DefaultSection && key.Name() == iniIncludeKey {
+				continue
+			}
+
+			var value, err = d.decryptValue(key.Value())
+			if err != nil {
+				return err
+			}
+			values[section.Name()+"."+key.Name()] = value
 		}
 	}
 
+	for k, v := range values {
+		c.set(k, Value{value: v, priority: priority, strict: false, source: source})
+	}
+
 	return nil
 }
 
 // ReadENV reads the config values from a byte array under ENV format.
 func (c *Config) ReadENV(priority int, b []byte) error {
+	return c.readENV(priority, b, "")
+}
+
+// readENV reads the config values from a byte array under ENV format. The
+// values are marked as read from the source.
+func (c *Config) readENV(priority int, b []byte, source string) error {
 	var envmap, err = godotenv.Unmarshal(string(b))
 	if err != nil {
 		return ConfigError.New(err)
 	}
 
+	var d = c.newDecrypter()
+	var values = make(map[string]any, len(envmap))
 	for k, v := range envmap {
-		c.Set(k, v, priority, false)
+		if values[k], err = d.decryptValue(v); err != nil {
+			return err
+		}
+	}
+
+	for k, v := range values {
+		c.set(k, Value{value: v, priority: priority, strict: false, source: source})
 	}
 
 	return nil
 }
 
 // ReadBytes reads the config values from a bytes array under any format.
+//
+// Include directives are only resolved when reading files:
+//    JSON: {"$include": ["common.json", "db/*.json"]}
+//    INI:  include = common.ini, db/*.ini (outside of any section)
+//    ENV:  #include common.env
+// Paths are relative to the including file and may contain glob patterns.
+// When reading bytes, these directives are ignored.
 func (c *Config) ReadBytes(format Format, priority int, b []byte) error {
+	return c.readBytes(format, priority, b, "")
+}
+
+// readBytes reads the config values from a bytes array under any format. The
+// values are marked as read from the source.
+func (c *Config) readBytes(format Format, priority int, b []byte, source string) error {
 	switch format {
 	case JSON:
-		return c.ReadJSON(priority, b)
+		return c.readJSON(priority, b, source)
 	case INI:
-		return c.ReadINI(priority, b)
+		return c.readINI(priority, b, source)
 	case ENV:
-		return c.ReadENV(priority, b)
+		return c.readENV(priority, b, source)
 	default:
 		return FormatError.New("unsupported format")
 	}
@@ -364,21 +811,54 @@ func (c *Config) ReadBytes(format Format, priority int, b []byte) error {
 
 // ReadFile reads the config values from a file. If watch is true, it will
 // reload config when the file is changed.
-func (c *Config) ReadFile(filename string, watch bool) error {
-	var fileFormat = UnknownFormat
-	for ext, format := range extensions {
-		if strings.HasSuffix(filename, ext) {
-			fileFormat = format
-		}
+//
+// The format is chosen by the extension. If the extension is unknown, the
+// format is detected from the content (see ReadReader).
+//
+// If the file cannot be watched by inotify, it falls back to polling the file
+// with the interval set by SetPollInterval.
+//
+// A file can include other files (see ReadBytes for the syntax of each
+// format). Included files are read before the including file, so the including
+// file overrides them at the same priority. They are watched if the including
+// file is watched.
+//
+// Options override the format, the priority and the watching of the file (see
+// ReadOption).
+func (c *Config) ReadFile(filename string, watch bool, opts ...ReadOption) error {
+	var o = newReadOptions(opts)
+	c.setSourceFormat(filepath.Clean(filename), o.format)
+	var priority = o.getPriority(getPriority(filename))
+	return c.readWatchedFile(filename, priority, o.getWatch(watch), o.interval)
+}
+
+// readWatchedFile reads a file with the priority. If watch is true, the file is
+// polled every interval, or watched by inotify if the interval is zero.
+func (c *Config) readWatchedFile(
+	filename string, priority int, watch bool, interval time.Duration,
+) error {
+	if watch && interval > 0 {
+		return c.readPolledFile(filename, priority, interval)
 	}
+	return c.readFile(filename, priority, watch, nil)
+}
 
-	if fileFormat == UnknownFormat {
-		return FormatError.Newf("unknown extension: %s", filename)
+// readFile reads the config values from a file with the priority. The stack
+// contains files being read, it is used to detect include cycles.
+func (c *Config) readFile(filename string, priority int, watch bool, stack []string) error {
+	if filename == "" {
+		return FormatError.New("empty filename")
 	}
 
+	// Record the priority before reading, so the file is reloaded with it even
+	// if it does not exist yet.
+	c.setFilePriority(filename, priority)
+
 	if watch {
 		if err := c.watchFile(filename); err != nil {
-			return err
+			logger.Event("watch-fallback").
+				Field("filename", filename).Field("error", err).Warning()
+			return c.readPolledFile(filename, priority, c.getPollInterval())
 		}
 	}
 
@@ -387,110 +867,152 @@ func (c *Config) ReadFile(filename string, watch bool) error {
 			return ConfigError.New(err)
 		}
 	} else {
-		if err := c.ReadBytes(fileFormat, getPriority(filename), data); err != nil {
+		if err := c.applyFile(filename, priority, data, watch, stack); err != nil {
 			return err
 		}
+		c.setFileHash(filename, data)
 	}
 
 	return nil
 }
 
-// ReadS3 reads a file from AWS S3 bucket and watch for their changes every
-// duration. Set the duration as zero if no need to watch the change.
-//
-// You must provide the aws credentials in ~/.aws/credentials. The AWS_REGION
-// is required.
-func (c *Config) ReadS3(url string, d time.Duration) error {
-	var fileFormat = UnknownFormat
-	for ext, format := range extensions {
-		if strings.HasSuffix(url, ext) {
-			fileFormat = format
+// applyFile reads the files included by the file, then reads the content of
+// the file.
+func (c *Config) applyFile(filename string, priority int, data []byte, watch bool, stack []string) error {
+	var source = filepath.Clean(filename)
+	for _, s := range stack {
+		if s == source {
+			return FormatError.Newf("include cycle: %s", strings.Join(append(stack, source), " -> "))
 		}
 	}
+	stack = append(stack, source)
 
-	if fileFormat == UnknownFormat {
-		return FormatError.Newf("unknown extension: %s", url)
+	var format, err = c.detectFileFormat(filename, data)
+	if err != nil {
+		return err
 	}
 
-	if !strings.HasPrefix(url, "s3://") {
-		return FormatError.Newf("can not parse the s3 url %s", url)
+	if err := c.readIncludes(filename, format, priority, data, watch, stack); err != nil {
+		return err
 	}
 
-	var path = url[5:]
-	var bucket, item, found = strings.Cut(path, "/")
-	if !found {
-		return FormatError.Newf("not found item in path %s", path)
-	}
+	c.setFilePriority(filename, priority)
 
-	var sess, err = session.NewSessionWithOptions(session.Options{
-		SharedConfigState: session.SharedConfigEnable,
+	return c.readBytes(format, priority, data, source)
+}
+
+// setFilePriority records the priority which the file is read with.
+func (c *Config) setFilePriority(filename string, priority int) {
+	c.lock.WLockFunc(func() {
+		c.filePriorities[filepath.Clean(filename)] = priority
 	})
+}
 
-	if err != nil {
-		return ConfigError.New(err)
+// getFilePriority returns the priority which the file was read with. If the
+// file has not been read yet, the priority is extracted from its name.
+func (c *Config) getFilePriority(filename string) int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if priority, ok := c.filePriorities[filepath.Clean(filename)]; ok {
+		return priority
 	}
+	return getPriority(filename)
+}
 
-	var downloader = s3manager.NewDownloader(sess)
-	var buf = aws.NewWriteAtBuffer([]byte{})
-	_, err = downloader.Download(
-		buf,
-		&s3.GetObjectInput{
-			Bucket: aws.String(bucket),
-			Key:    aws.String(item),
-		})
+// getSourceFormat returns the format given to the source by WithFormat. If no
+// format was given, it is chosen by the extension of name.
+func (c *Config) getSourceFormat(source, name string) Format {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 
-	if d != 0 {
-		c.lock.Lock()
-		c.timerWatchers[url] = time.AfterFunc(d, func() { c.ReadS3(url, d) })
-		c.lock.Unlock()
+	if format, ok := c.formats[source]; ok {
+		return format
 	}
+	return getFormat(name)
+}
 
-	if err != nil {
-		if d == 0 {
-			return ConfigError.New(err)
+// detectFileFormat returns the format of the file. If neither a format was
+// given nor the extension is known, it is detected from the content.
+func (c *Config) detectFileFormat(filename string, data []byte) (Format, error) {
+	var format = c.getSourceFormat(filepath.Clean(filename), filename)
+	if format == UnknownFormat {
+		if format = detectFormat(data); format == UnknownFormat {
+			return UnknownFormat, FormatError.Newf("cannot detect the format of %s", filename)
 		}
-		return nil
 	}
-
-	return c.ReadBytes(fileFormat, getPriority(url), buf.Bytes())
+	return format, nil
 }
 
 // LoadEnv loads all environment variables and watch for their changes every
-// duration. Set the duration as zero if no need to watch the change.
+// duration. Set the duration as zero if no need to watch the change. Encrypted
+// values are decrypted with the KeyProvider of Config.
 func (c *Config) LoadEnv(d time.Duration) error {
+	return c.loadEnv(maxPriority, d)
+}
+
+// loadEnv loads all environment variables with the priority and watch for
+// their changes every duration.
+func (c *Config) loadEnv(priority int, d time.Duration) error {
+	// The next load is scheduled first, so a failed load does not stop the
+	// watching.
+	if d != 0 {
+		c.lock.Lock()
+		c.timerWatchers["env"] = time.AfterFunc(d, func() { c.loadEnv(priority, d) })
+		c.lock.Unlock()
+	}
+
 	var envs = os.Environ()
+	var dec = c.newDecrypter()
+	var values = make(map[string]any, len(envs))
 	for i := range envs {
 		var key, value, found = strings.Cut(envs[i], "=")
 		if !found {
 			return FormatError.Newf("invalid environment variable %s", envs[i])
 		}
-		c.Set(key, value, maxPriority, false)
+
+		var err error
+		if values[key], err = dec.decryptValue(value); err != nil {
+			return err
+		}
 	}
 
-	if d != 0 {
-		c.lock.Lock()
-		c.timerWatchers["env"] = time.AfterFunc(d, func() { c.LoadEnv(d) })
-		c.lock.Unlock()
+	for k, v := range values {
+		c.set(k, Value{value: v, priority: priority, strict: false, source: "env"})
 	}
 
 	return nil
 }
 
-// Read reads the config with any instance. If the instance is s3 url or
-// environment variable, the watchInterval is used to choose the time interval
-// for watching changes. If the instance is file path, it will watch the change
-// if watchInterval > 0.
-func (c *Config) Read(path string) error {
+// Read reads the config with any instance. If the instance is s3 url, http(s)
+// url or environment variable, the watchInterval is used to choose the time
+// interval for watching changes. If the instance is file path, it will watch
+// the change if watchInterval > 0.
+//
+// The instance "-" or "stdin" reads the standard input until EOF, detecting
+// its format from the content (see ReadReader).
+//
+// Options customize how the instance is read (see ReadOption), for example:
+//    config.Read("/etc/app/config", xyconfig.WithFormat(xyconfig.INI))
+//    config.Read("s3://bucket/app.json", xyconfig.WithPriority(30), xyconfig.WithWatch())
+func (c *Config) Read(path string, opts ...ReadOption) error {
+	c.lock.RLock()
+	var interval = c.watchInterval
+	c.lock.RUnlock()
+
 	switch {
 	case path == "env":
-		return c.LoadEnv(c.watchInterval)
+		var o = newReadOptions(opts)
+		return c.loadEnv(o.getPriority(maxPriority), o.getInterval(interval))
+	case path == "-", path == stdinSource:
+		var o = newReadOptions(opts)
+		return c.readStdin(o.format, o.getPriority(0))
 	case strings.HasPrefix(path, "s3://"):
-		return c.ReadS3(path, c.watchInterval)
+		return c.ReadS3(path, interval, opts...)
+	case strings.HasPrefix(path, "http://"), strings.HasPrefix(path, "https://"):
+		return c.ReadHTTP(path, interval, opts...)
 	default:
-		if c.watchInterval > 0 {
-			return c.ReadFile(path, true)
-		}
-		return c.ReadFile(path, false)
+		return c.ReadFile(path, interval > 0, opts...)
 	}
 }
 
@@ -503,6 +1025,7 @@ func (c *Config) Get(key string) (Value, bool) {
 	var before, after, found = strings.Cut(key, ".")
 	var v, ok = c.config[before]
 	if !found {
+		v.sensitive = ok && isSensitive(c.name+"."+key)
 		return v, ok
 	}
 
@@ -529,17 +1052,32 @@ func (c *Config) MustGet(key string) Value {
 func (c *Config) GetDefault(key string, def any) Value {
 	var v, ok = c.Get(key)
 	if !ok {
-		return Value{value: def, strict: true}
+		return Value{value: def, strict: true, sensitive: c.IsSensitive(key)}
 	}
 	return v
 }
 
-// ToMap converts current config to map.
+// ToMap converts current config to map. Values of sensitive keys are replaced
+// by ******.
 func (c *Config) ToMap() map[string]any {
+	return c.toMap(true)
+}
+
+// toMap converts current config to map. If redact is true, values of sensitive
+// keys are replaced by ******. Otherwise, values which were read encrypted are
+// kept in their encrypted form.
+func (c *Config) toMap(redact bool) map[string]any {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
 	var result = make(map[string]any)
 	for k, v := range c.config {
-		if c, ok := v.AsConfig(); ok {
-			result[k] = c.ToMap()
+		if sub, ok := v.AsConfig(); ok {
+			result[k] = sub.toMap(redact)
+		} else if redact && isSensitive(c.name+"."+k) {
+			result[k] = redacted
+		} else if !redact && v.ciphertext != nil {
+			result[k] = v.ciphertext
 		} else {
 			result[k] = v.value
 		}
@@ -571,15 +1109,7 @@ func (c *Config) initWatcher() error {
 					return
 				}
 
-				if event.Has(fsnotify.Write) {
-					var err = c.ReadFile(event.Name, false)
-					if err != nil {
-						logger.Event("reload-error").
-							Field("filename", event.Name).Field("error", err).Warning()
-					} else {
-						logger.Event("reload-config").Field("filename", event.Name).Info()
-					}
-				}
+				c.handleEvent(event)
 
 			case err, ok := <-watcherErrors:
 				if !ok {
@@ -594,8 +1124,48 @@ func (c *Config) initWatcher() error {
 	return nil
 }
 
+// handleEvent handles an event of the watcher. Editors and deployment tools
+// often save a file by writing a temporary file and renaming it over the
+// original one, so Create and Rename events are handled as well as Write.
+func (c *Config) handleEvent(event fsnotify.Event) {
+	var filename = filepath.Clean(event.Name)
+	if filepath.Base(filename) == configMapData && event.Has(fsnotify.Create) {
+		c.reloadConfigMap(filepath.Dir(filename))
+		return
+	}
+
+	c.lock.RLock()
+	var _, watched = c.watchedFiles[filename]
+	var wd, inDir = c.watchedDirs[filepath.Dir(filename)]
+	c.lock.RUnlock()
+
+	if !watched {
+		// A new file is added to a directory watched by ReadDir.
+		if !inDir || !isDirFile(filename, wd.pattern, wd.options.format) ||
+			!event.Has(fsnotify.Create) {
+			return
+		}
+
+		c.setSourceFormat(filename, wd.options.format)
+		c.setFilePriority(filename, wd.options.getPriority(getPriority(filename)))
+
+		if err := c.watchFile(filename); err != nil {
+			logger.Event("watch-error").
+				Field("filename", filename).Field("error", err).Warning()
+			return
+		}
+	}
+
+	// The file is reloaded with its final state, it may be removed, or be
+	// replaced by another one right after it is removed.
+	c.scheduleReload(filename)
+}
+
 // watchFile adds filename to watcher. If the watcher has not initialized yet,
 // create a new one.
+//
+// The watcher watches the parent directory of filename instead of filename
+// itself, so the watching is not lost when the file is removed or replaced.
 func (c *Config) watchFile(filename string) error {
 	var watcher = c.lock.RLockFunc(func() any {
 		return c.watcher
@@ -610,43 +1180,86 @@ func (c *Config) watchFile(filename string) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	// Create the file if it does not exist, so the watcher will not raise an
-	// exception.
-	var ferr error
-	if _, ferr = os.Open(filename); os.IsNotExist(ferr) {
-		var dir = filepath.Dir(filename)
-		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-			return err
+	var name = filepath.Clean(filename)
+	if _, ok := c.watchedFiles[name]; ok {
+		return nil
+	}
+
+	// Create the directory if it does not exist, so the watcher will not raise
+	// an exception.
+	var dir = filepath.Dir(name)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return ConfigError.New(err)
+	}
+
+	// Files in a ConfigMap volume never change directly, the volume directory
+	// is also watched to detect the swap of its data.
+	var volume = findConfigMapVolume(name)
+	for _, d := range []string{dir, volume} {
+		if d != "" && !c.isDirWatched(d) {
+			if err := c.watcher.Add(d); err != nil {
+				return ConfigError.New(err)
+			}
 		}
+	}
 
-		if _, err := os.OpenFile(filename, os.O_CREATE, 0666); err != nil {
-			return err
+	c.watchedFiles[name] = volume
+	return nil
+}
+
+// isDirWatched returns true if any file in the directory or any file in a
+// ConfigMap volume at the directory is being watched. This method is not
+// thread-safe, the caller must hold the lock.
+func (c *Config) isDirWatched(dir string) bool {
+	if _, ok := c.watchedDirs[dir]; ok {
+		return true
+	}
+
+	for name, volume := range c.watchedFiles {
+		if filepath.Dir(name) == dir || volume == dir {
+			return true
 		}
 	}
+	return false
+}
 
-	if err := c.watcher.Add(filename); err != nil {
-		return ConfigError.New(err)
+// isEqual reports whether two values are equal. Unlike the == operator, it does
+// not panic with uncomparable values such as arrays.
+func isEqual(a, b any) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
 	}
 
-	// Remove the file after adding to watcher.
-	if os.IsNotExist(ferr) {
-		if err := os.Remove(filename); err != nil {
-			return err
+	if a == nil || reflect.TypeOf(a).Comparable() {
+		return a == b
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// getFormat returns the format corresponding to the extension of filename.
+func getFormat(filename string) Format {
+	for ext, format := range extensions {
+		if strings.HasSuffix(filename, ext) {
+			return format
 		}
 	}
+	return UnknownFormat
+}
 
-	return nil
+// priorityExp matches filenames containing a priority.
+var priorityExp = regexp.MustCompile(`^(\d+)-\w+.(env|ini|json)$`)
+
+// hasPriority returns true if filename contains a priority.
+func hasPriority(filename string) bool {
+	return priorityExp.MatchString(filepath.Base(filename))
 }
 
 // getPriority extracts the priority from filename.
 func getPriority(filename string) int {
-	var exp, err = regexp.Compile(`^(\d+)-\w+.(env|ini|json)$`)
-	if err != nil {
-		return 0
-	}
-
 	var priority = 0
-	if b := exp.FindAllSubmatch([]byte(filepath.Base(filename)), -1); b != nil {
+	if b := priorityExp.FindAllSubmatch([]byte(filepath.Base(filename)), -1); b != nil {
+		var err error
 		priority, err = strconv.Atoi(string(b[0][1]))
 		if err != nil {
 			return 0
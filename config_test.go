@@ -23,6 +23,7 @@ package xyconfig_test
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -180,8 +181,11 @@ func TestConfigReadByteUnknown(t *testing.T) {
 }
 
 func TestConfigReadFileUnknownExt(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "foo.bar")
+	ioutil.WriteFile(filename, []byte("not a config"), 0644)
+
 	var cfg = xyconfig.GetConfig(t.Name())
-	var err = cfg.ReadFile("foo.bar", false)
+	var err = cfg.ReadFile(filename, false)
 
 	xycond.ExpectError(err, xyconfig.FormatError).Test(t)
 }
@@ -238,7 +242,11 @@ func TestConfigReadFileWithPriority(t *testing.T) {
 }
 
 func TestConfigReadS3UnknownExt(t *testing.T) {
+	var client = newFakeS3()
+	client.put("bucket", "abc.unk", "not a config", `"1"`)
+
 	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetS3Client(client)
 	var err = cfg.ReadS3("s3://bucket/abc.unk", 0)
 	xycond.ExpectError(err, xyconfig.FormatError).Test(t)
 }
@@ -342,3 +350,129 @@ func TestConfigUnWatch(t *testing.T) {
 	xycond.ExpectNil(cfg.UnWatch(t.Name() + ".json")).Test(t)
 	xycond.ExpectError(cfg.UnWatch("foo.json"), xyconfig.ConfigError).Test(t)
 }
+
+// eventually waits until the condition is true or the timeout is exceeded.
+func eventually(cond func() bool) bool {
+	for i := 0; i < 300; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+func TestConfigReadFileWithAtomicRename(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "config.json")
+	ioutil.WriteFile(filename, []byte(`{"foo": "bar"}`), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadFile(filename, true)).Test(t)
+
+	for _, value := range []string{"buzz", "bizz"} {
+		ioutil.WriteFile(filename+".tmp", []byte(`{"foo": "`+value+`"}`), 0644)
+		os.Rename(filename+".tmp", filename)
+
+		xycond.ExpectTrue(eventually(func() bool {
+			return cfg.MustGet("foo").MustString() == value
+		})).Test(t)
+	}
+}
+
+func TestConfigReadFileWithRemove(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "config.json")
+	ioutil.WriteFile(filename, []byte(`{"foo": "bar", "sub": {"buzz": 1}}`), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	cfg.Set("other", "value", 0, true)
+	xycond.ExpectNil(cfg.ReadFile(filename, true)).Test(t)
+
+	var events = make(chan xyconfig.Event, 10)
+	cfg.AddHook("foo", func(e xyconfig.Event) { events <- e })
+
+	os.Remove(filename)
+	xycond.ExpectTrue(eventually(func() bool {
+		var _, ok = cfg.Get("foo")
+		return !ok
+	})).Test(t)
+
+	var _, ok = cfg.Get("sub.buzz")
+	xycond.ExpectFalse(ok).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("other").MustString(), "value").Test(t)
+
+	var e = <-events
+	xycond.ExpectEqual(e.Old.MustString(), "bar").Test(t)
+	xycond.ExpectTrue(e.New.IsNil()).Test(t)
+
+	// The watching continues after the file is created again.
+	ioutil.WriteFile(filename, []byte(`{"foo": "buzz"}`), 0644)
+	xycond.ExpectTrue(eventually(func() bool {
+		var v, ok = cfg.Get("foo")
+		return ok && v.MustString() == "buzz"
+	})).Test(t)
+}
+
+func TestConfigReadFileWithRemoveFallback(t *testing.T) {
+	var dir = t.TempDir()
+	var base, local = filepath.Join(dir, "10-base.json"), filepath.Join(dir, "20-local.json")
+	ioutil.WriteFile(base, []byte(`{"a": "base", "b": "base"}`), 0644)
+	ioutil.WriteFile(local, []byte(`{"a": "local"}`), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadFile(base, true)).Test(t)
+	xycond.ExpectNil(cfg.ReadFile(local, true)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("a").MustString(), "local").Test(t)
+
+	var events = make(chan xyconfig.Event, 10)
+	cfg.AddHook("a", func(e xyconfig.Event) { events <- e })
+
+	// The value of the lower priority file is restored.
+	os.Remove(local)
+	xycond.ExpectTrue(eventually(func() bool {
+		var v, ok = cfg.Get("a")
+		return ok && v.MustString() == "base"
+	})).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("b").MustString(), "base").Test(t)
+
+	var e = <-events
+	xycond.ExpectEqual(e.Old.MustString(), "local").Test(t)
+	xycond.ExpectEqual(e.New.MustString(), "base").Test(t)
+
+	os.Remove(base)
+	xycond.ExpectTrue(eventually(func() bool {
+		var _, ok = cfg.Get("a")
+		return !ok
+	})).Test(t)
+}
+
+func TestConfigReadFileIgnoreSwapFile(t *testing.T) {
+	var dir = t.TempDir()
+	var filename = filepath.Join(dir, "config.json")
+	ioutil.WriteFile(filename, []byte(`{"foo": "bar"}`), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadFile(filename, true)).Test(t)
+
+	ioutil.WriteFile(filepath.Join(dir, ".config.json.swp"), []byte(`{"foo": "buzz"}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "config.json~"), []byte(`{"foo": "buzz"}`), 0644)
+	time.Sleep(50 * time.Millisecond)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+}
+
+func TestConfigReadFileWithArrayChange(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "config.json")
+	ioutil.WriteFile(filename, []byte(`{"foo": ["bar"]}`), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadFile(filename, true)).Test(t)
+
+	ioutil.WriteFile(filename, []byte(`{"foo": ["buzz"]}`), 0644)
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustArray()[0].MustString() == "buzz"
+	})).Test(t)
+}
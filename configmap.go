@@ -0,0 +1,119 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// configMapData is the symlink which Kubernetes swaps atomically when a
+// ConfigMap volume is updated. Files in the volume are symlinks through it:
+//
+//    /etc/config/app.json -> ..data/app.json
+//    /etc/config/..data -> ..2023_01_25_10_00_00.123456789
+const configMapData = "..data"
+
+// findConfigMapVolume returns the directory of the ConfigMap volume which
+// contains filename, or an empty string if filename is not in any volume. The
+// file is also found through symlinks pointing into the volume.
+func findConfigMapVolume(filename string) string {
+	var dirs = []string{filepath.Dir(filename)}
+	if resolved, err := filepath.EvalSymlinks(filename); err == nil {
+		// The resolved path is <volume>/..<timestamp>/<file>.
+		dirs = append(dirs, filepath.Dir(filepath.Dir(resolved)))
+	}
+
+	for _, dir := range dirs {
+		var info, err = os.Lstat(filepath.Join(dir, configMapData))
+		if err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return dir
+		}
+	}
+
+	return ""
+}
+
+// reloadConfigMap reloads all watched files in the ConfigMap volume.
+func (c *Config) reloadConfigMap(volume string) {
+	var filenames []string
+	c.lock.RLock()
+	for name, v := range c.watchedFiles {
+		if v == volume {
+			filenames = append(filenames, name)
+		}
+	}
+	c.lock.RUnlock()
+
+	if len(filenames) == 0 {
+		return
+	}
+
+	sortByPriority(filenames)
+	if err := c.reloadFiles(filenames); err != nil {
+		logger.Event("reload-error").
+			Field("volume", volume).Field("error", err).Warning()
+	} else {
+		logger.Event("reload-config").Field("volume", volume).Info()
+	}
+}
+
+// reloadFiles reloads files in one transaction. All files are read and parsed
+// before any value is applied, so a broken file does not leave the Config
+// partially updated. Files which no longer exist are removed from Config.
+func (c *Config) reloadFiles(filenames []string) error {
+	var contents = make([][]byte, len(filenames))
+	for i, filename := range filenames {
+		var data, err = ioutil.ReadFile(filename)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return ConfigError.New(err)
+		}
+
+		format, err := c.detectFileFormat(filename, data)
+		if err != nil {
+			return err
+		}
+
+		if _, _, err := unmarshalFlat(format, data); err != nil {
+			return err
+		}
+		contents[i] = data
+	}
+
+	for i, filename := range filenames {
+		if contents[i] == nil {
+			c.removeSource(filename)
+			continue
+		}
+
+		var err = c.applyFile(filename, c.getFilePriority(filename), contents[i], true, nil)
+		if err != nil {
+			return err
+		}
+		c.setFileHash(filename, contents[i])
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+// writeConfigMap simulates an update of a ConfigMap volume by Kubernetes.
+func writeConfigMap(t *testing.T, volume, version string, files map[string]string) {
+	var data = filepath.Join(volume, "..2023_"+version)
+	xycond.ExpectNil(os.Mkdir(data, 0755)).Test(t)
+	for name, content := range files {
+		ioutil.WriteFile(filepath.Join(data, name), []byte(content), 0644)
+		os.Symlink(filepath.Join("..data", name), filepath.Join(volume, name))
+	}
+
+	var tmp = filepath.Join(volume, "..data_tmp")
+	xycond.ExpectNil(os.Symlink(filepath.Base(data), tmp)).Test(t)
+	xycond.ExpectNil(os.Rename(tmp, filepath.Join(volume, "..data"))).Test(t)
+}
+
+func TestConfigReadFileConfigMap(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks are not supported")
+	}
+
+	var volume = t.TempDir()
+	writeConfigMap(t, volume, "1", map[string]string{
+		"10-app.json": `{"foo": "bar", "app": {"timeout": 1}}`,
+		"20-app.ini":  "[app]\ntimeout = 2",
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadFile(filepath.Join(volume, "10-app.json"), true)).Test(t)
+	xycond.ExpectNil(cfg.ReadFile(filepath.Join(volume, "20-app.ini"), true)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("app.timeout").MustInt(), 2).Test(t)
+
+	writeConfigMap(t, volume, "2", map[string]string{
+		"10-app.json": `{"foo": "buzz", "app": {"timeout": 1}}`,
+		"20-app.ini":  "[app]\ntimeout = 3",
+	})
+
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustString() == "buzz"
+	})).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("app.timeout").MustInt(), 3).Test(t)
+}
+
+func TestConfigReadFileConfigMapThroughSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks are not supported")
+	}
+
+	var volume = t.TempDir()
+	writeConfigMap(t, volume, "1", map[string]string{"app.json": `{"foo": "bar"}`})
+
+	var link = filepath.Join(t.TempDir(), "app.json")
+	xycond.ExpectNil(os.Symlink(filepath.Join(volume, "app.json"), link)).Test(t)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadFile(link, true)).Test(t)
+
+	writeConfigMap(t, volume, "2", map[string]string{"app.json": `{"foo": "buzz"}`})
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustString() == "buzz"
+	})).Test(t)
+}
+
+func TestConfigReadFileConfigMapInvalid(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks are not supported")
+	}
+
+	var volume = t.TempDir()
+	writeConfigMap(t, volume, "1", map[string]string{
+		"a.json": `{"foo": "bar"}`,
+		"b.json": `{"buzz": "bizz"}`,
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadFile(filepath.Join(volume, "a.json"), true)).Test(t)
+	xycond.ExpectNil(cfg.ReadFile(filepath.Join(volume, "b.json"), true)).Test(t)
+
+	// No file is applied if any file is broken.
+	writeConfigMap(t, volume, "2", map[string]string{
+		"a.json": `{"foo": "buzz"}`,
+		"b.json": `{"error`,
+	})
+
+	time.Sleep(100 * time.Millisecond)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+}
@@ -0,0 +1,338 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// keySize is the size of AES-256 keys.
+const keySize = 32
+
+// Encrypted values have the form ENC[AES256_GCM,<base64 of nonce+ciphertext>].
+const (
+	encryptedPrefix = "ENC[AES256_GCM,"
+	encryptedSuffix = "]"
+)
+
+// KeyProvider provides the key for decrypting encrypted values.
+type KeyProvider interface {
+	// Key returns a 32-byte AES-256 key.
+	Key() ([]byte, error)
+}
+
+type keyFile string
+
+// KeyFile returns a KeyProvider which reads the key from a file. The file
+// contains either the raw 32-byte key or its base64 encoding.
+func KeyFile(path string) KeyProvider {
+	return keyFile(path)
+}
+
+func (k keyFile) Key() ([]byte, error) {
+	var b, err = ioutil.ReadFile(string(k))
+	if err != nil {
+		return nil, CryptoError.New(err)
+	}
+	return parseKey(b)
+}
+
+type keyEnv string
+
+// KeyEnv returns a KeyProvider which reads the base64-encoded key from an
+// environment variable.
+func KeyEnv(name string) KeyProvider {
+	return keyEnv(name)
+}
+
+func (k keyEnv) Key() ([]byte, error) {
+	var s, ok = os.LookupEnv(string(k))
+	if !ok {
+		return nil, CryptoError.Newf("environment variable %s is not set", k)
+	}
+	return parseKey([]byte(s))
+}
+
+// GenerateKey returns a new random key under base64 encoding, which is
+// suitable for KeyFile and KeyEnv.
+func GenerateKey() (string, error) {
+	var key = make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", CryptoError.New(err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// IsEncrypted returns true if the string is an encrypted value.
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, encryptedPrefix) && strings.HasSuffix(s, encryptedSuffix)
+}
+
+// Encrypt encrypts the plaintext with the key. The result can be put into
+// config files in place of the plaintext.
+func Encrypt(key []byte, plaintext string) (string, error) {
+	var gcm, err = newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	var nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", CryptoError.New(err)
+	}
+
+	var data = gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(data) + encryptedSuffix, nil
+}
+
+// Decrypt decrypts a value produced by Encrypt.
+func Decrypt(key []byte, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", CryptoError.New("the value is not encrypted")
+	}
+
+	var gcm, err = newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	var encoded = value[len(encryptedPrefix) : len(value)-len(encryptedSuffix)]
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", CryptoError.Newf("invalid encrypted value (%v)", err)
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return "", CryptoError.New("encrypted value is too short")
+	}
+
+	var nonce, ciphertext = data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", CryptoError.New("cannot decrypt value, the key may be wrong")
+	}
+
+	return string(plaintext), nil
+}
+
+// SetKeyProvider sets the KeyProvider used to decrypt encrypted values when
+// reading config data.
+func (c *Config) SetKeyProvider(kp KeyProvider) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.keyProvider = kp
+}
+
+// Encrypt encrypts the plaintext with the key of KeyProvider.
+func (c *Config) Encrypt(plaintext string) (string, error) {
+	var kp = c.getKeyProvider()
+	if kp == nil {
+		return "", CryptoError.New("no key provider")
+	}
+
+	var key, err = kp.Key()
+	if err != nil {
+		return "", err
+	}
+
+	return Encrypt(key, plaintext)
+}
+
+// getKeyProvider returns the current KeyProvider of Config.
+func (c *Config) getKeyProvider() KeyProvider {
+	var kp = c.lock.RLockFunc(func() any {
+		return c.keyProvider
+	})
+
+	if kp == nil {
+		return nil
+	}
+	return kp.(KeyProvider)
+}
+
+// decrypter decrypts values in a read. The key is only loaded once when the
+// first encrypted value is found.
+type decrypter struct {
+	provider KeyProvider
+	key      []byte
+}
+
+// newDecrypter creates a decrypter with the current KeyProvider of Config.
+func (c *Config) newDecrypter() *decrypter {
+	return &decrypter{provider: c.getKeyProvider()}
+}
+
+// decryptString returns the plaintext of s if s is encrypted, otherwise, it
+// returns s.
+func (d *decrypter) decryptString(s string) (string, error) {
+	if !IsEncrypted(s) {
+		return s, nil
+	}
+
+	if d.key == nil {
+		if d.provider == nil {
+			return "", CryptoError.New("found an encrypted value but no key provider")
+		}
+
+		var key, err = d.provider.Key()
+		if err != nil {
+			return "", err
+		}
+		d.key = key
+	}
+
+	return Decrypt(d.key, s)
+}
+
+// decrypted is a value decrypted by decrypter. It keeps the encrypted form, so
+// the value can be written back without exposing the plaintext. Config.set
+// unwraps it.
+type decrypted struct {
+	plain  any
+	cipher any
+}
+
+// decryptValue returns a decrypted value if s is encrypted, otherwise, it
+// returns s.
+func (d *decrypter) decryptValue(s string) (any, error) {
+	if !IsEncrypted(s) {
+		return s, nil
+	}
+
+	var plain, err = d.decryptString(s)
+	if err != nil {
+		return nil, err
+	}
+	return decrypted{plain: plain, cipher: s}, nil
+}
+
+// decrypt decrypts all strings in v, including strings nested in arrays and
+// maps. Encrypted strings, and arrays containing them at any depth, are
+// returned as decrypted values. Maps keep a decrypted value for each key,
+// since their keys are read as separate values.
+func (d *decrypter) decrypt(v any) (any, error) {
+	switch t := v.(type) {
+	case string:
+		return d.decryptValue(t)
+	case []any:
+		var plain, encrypted, err = d.decryptTree(t)
+		if err != nil {
+			return nil, err
+		}
+		if encrypted {
+			return decrypted{plain: plain, cipher: t}, nil
+		}
+		return plain, nil
+	case map[string]any:
+		var result = make(map[string]any, len(t))
+		for k := range t {
+			var e, err = d.decrypt(t[k])
+			if err != nil {
+				return nil, err
+			}
+			result[k] = e
+		}
+		return result, nil
+	default:
+		return v, nil
+	}
+}
+
+// decryptTree decrypts all strings in v, including strings nested in arrays
+// and maps. The latter returned value is true if any string was encrypted.
+func (d *decrypter) decryptTree(v any) (any, bool, error) {
+	switch t := v.(type) {
+	case string:
+		if !IsEncrypted(t) {
+			return t, false, nil
+		}
+		var plain, err = d.decryptString(t)
+		return plain, err == nil, err
+	case []any:
+		var result = make([]any, len(t))
+		var encrypted = false
+		for i := range t {
+			var e, enc, err = d.decryptTree(t[i])
+			if err != nil {
+				return nil, false, err
+			}
+			result[i], encrypted = e, encrypted || enc
+		}
+		return result, encrypted, nil
+	case map[string]any:
+		var result = make(map[string]any, len(t))
+		var encrypted = false
+		for k := range t {
+			var e, enc, err = d.decryptTree(t[k])
+			if err != nil {
+				return nil, false, err
+			}
+			result[k], encrypted = e, encrypted || enc
+		}
+		return result, encrypted, nil
+	default:
+		return v, false, nil
+	}
+}
+
+// newGCM creates an AES-256 GCM cipher with the key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, CryptoError.Newf("the key must be %d bytes, got %d", keySize, len(key))
+	}
+
+	var block, err = aes.NewCipher(key)
+	if err != nil {
+		return nil, CryptoError.New(err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, CryptoError.New(err)
+	}
+
+	return gcm, nil
+}
+
+// parseKey parses a raw or base64-encoded key.
+func parseKey(b []byte) ([]byte, error) {
+	if len(b) == keySize {
+		return b, nil
+	}
+
+	var key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, CryptoError.Newf("invalid key (%v)", err)
+	}
+
+	if len(key) != keySize {
+		return nil, CryptoError.Newf("the key must be %d bytes, got %d", keySize, len(key))
+	}
+
+	return key, nil
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"encoding/base64"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+func newTestKey(t *testing.T) []byte {
+	var encoded, err = xyconfig.GenerateKey()
+	xycond.ExpectNil(err).Test(t)
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	xycond.ExpectNil(err).Test(t)
+	return key
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	var key = newTestKey(t)
+	var encrypted, err = xyconfig.Encrypt(key, "secret")
+	xycond.ExpectNil(err).Test(t)
+	xycond.ExpectTrue(xyconfig.IsEncrypted(encrypted)).Test(t)
+
+	plaintext, err := xyconfig.Decrypt(key, encrypted)
+	xycond.ExpectNil(err).Test(t)
+	xycond.ExpectEqual(plaintext, "secret").Test(t)
+}
+
+func TestDecryptWrongKey(t *testing.T) {
+	var encrypted, err = xyconfig.Encrypt(newTestKey(t), "secret")
+	xycond.ExpectNil(err).Test(t)
+
+	_, err = xyconfig.Decrypt(newTestKey(t), encrypted)
+	xycond.ExpectError(err, xyconfig.CryptoError).Test(t)
+}
+
+func TestEncryptInvalidKey(t *testing.T) {
+	var _, err = xyconfig.Encrypt([]byte("short"), "secret")
+	xycond.ExpectError(err, xyconfig.CryptoError).Test(t)
+}
+
+func TestConfigReadJSONEncrypted(t *testing.T) {
+	var key = newTestKey(t)
+	var encrypted, _ = xyconfig.Encrypt(key, "secret")
+
+	var keyfile = filepath.Join(t.TempDir(), "key")
+	ioutil.WriteFile(keyfile, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetKeyProvider(xyconfig.KeyFile(keyfile))
+	var err = cfg.ReadJSON(0, []byte(`{"db": {"password": "`+encrypted+`"}, "list": ["`+encrypted+`"]}`))
+
+	xycond.ExpectNil(err).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("db.password").MustString(), "secret").Test(t)
+	xycond.ExpectEqual(cfg.MustGet("list").MustArray()[0].MustString(), "secret").Test(t)
+}
+
+func TestConfigReadJSONEncryptedInArray(t *testing.T) {
+	var key = newTestKey(t)
+	var encrypted, _ = xyconfig.Encrypt(key, "secret")
+	os.Setenv(t.Name(), base64.StdEncoding.EncodeToString(key))
+	defer os.Unsetenv(t.Name())
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetKeyProvider(xyconfig.KeyEnv(t.Name()))
+	var err = cfg.ReadJSON(0, []byte(`{"list": [{"p": "`+encrypted+`"}, "plain"]}`))
+	xycond.ExpectNil(err).Test(t)
+
+	var list = cfg.ToMap()["list"].([]any)
+	xycond.ExpectEqual(list[0].(map[string]any)["p"], "secret").Test(t)
+	xycond.ExpectEqual(list[1], "plain").Test(t)
+
+	// The array is written back with its ciphertext.
+	data, err := cfg.Marshal(xyconfig.JSON)
+	xycond.ExpectNil(err).Test(t)
+	xycond.ExpectIn(encrypted, string(data)).Test(t)
+
+	var read = xyconfig.GetConfig(t.Name() + "-read")
+	read.SetKeyProvider(xyconfig.KeyEnv(t.Name()))
+	xycond.ExpectNil(read.ReadJSON(0, data)).Test(t)
+	list = read.ToMap()["list"].([]any)
+	xycond.ExpectEqual(list[0].(map[string]any)["p"], "secret").Test(t)
+}
+
+func TestConfigReadINIEncrypted(t *testing.T) {
+	var key = newTestKey(t)
+	var encrypted, _ = xyconfig.Encrypt(key, "secret")
+	os.Setenv(t.Name(), base64.StdEncoding.EncodeToString(key))
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetKeyProvider(xyconfig.KeyEnv(t.Name()))
+	var err = cfg.ReadINI(0, []byte("[db]\npassword="+encrypted))
+
+	xycond.ExpectNil(err).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("db.password").MustString(), "secret").Test(t)
+}
+
+func TestConfigReadENVEncrypted(t *testing.T) {
+	var key = newTestKey(t)
+	var encrypted, _ = xyconfig.Encrypt(key, "secret")
+	os.Setenv(t.Name(), base64.StdEncoding.EncodeToString(key))
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetKeyProvider(xyconfig.KeyEnv(t.Name()))
+	var err = cfg.ReadENV(0, []byte("password="+encrypted))
+
+	xycond.ExpectNil(err).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("password").MustString(), "secret").Test(t)
+}
+
+func TestConfigLoadEnvEncrypted(t *testing.T) {
+	var key = newTestKey(t)
+	var encrypted, _ = xyconfig.Encrypt(key, "secret")
+	os.Setenv(t.Name()+"_KEY", base64.StdEncoding.EncodeToString(key))
+	os.Setenv(t.Name(), encrypted)
+	defer os.Unsetenv(t.Name() + "_KEY")
+	defer os.Unsetenv(t.Name())
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectError(cfg.LoadEnv(0), xyconfig.CryptoError).Test(t)
+
+	cfg.SetKeyProvider(xyconfig.KeyEnv(t.Name() + "_KEY"))
+	xycond.ExpectNil(cfg.LoadEnv(0)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet(t.Name()).MustString(), "secret").Test(t)
+}
+
+func TestConfigReadFlagsEncrypted(t *testing.T) {
+	var key = newTestKey(t)
+	var encrypted, _ = xyconfig.Encrypt(key, "secret")
+	os.Setenv(t.Name(), base64.StdEncoding.EncodeToString(key))
+	defer os.Unsetenv(t.Name())
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetKeyProvider(xyconfig.KeyEnv(t.Name()))
+
+	var fs = flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	fs.String("db.password", "", "")
+	xycond.ExpectNil(fs.Parse([]string{"-db.password=" + encrypted})).Test(t)
+	xycond.ExpectNil(cfg.ReadFlags(fs, 0)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("db.password").MustString(), "secret").Test(t)
+}
+
+func TestConfigReadEncryptedWithoutKeyProvider(t *testing.T) {
+	var encrypted, _ = xyconfig.Encrypt(newTestKey(t), "secret")
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	var err = cfg.ReadJSON(0, []byte(`{"password": "`+encrypted+`"}`))
+
+	xycond.ExpectError(err, xyconfig.CryptoError).Test(t)
+	_, ok := cfg.Get("password")
+	xycond.ExpectFalse(ok).Test(t)
+}
+
+func TestConfigEncrypt(t *testing.T) {
+	var key = newTestKey(t)
+	os.Setenv(t.Name(), base64.StdEncoding.EncodeToString(key))
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetKeyProvider(xyconfig.KeyEnv(t.Name()))
+
+	var encrypted, err = cfg.Encrypt("secret")
+	xycond.ExpectNil(err).Test(t)
+
+	xycond.ExpectNil(cfg.ReadENV(0, []byte("password="+encrypted))).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("password").MustString(), "secret").Test(t)
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ReadDir reads all files in the directory whose names match the pattern (for
+// example, "*.json"). An empty pattern matches all files with supported
+// extensions. Files are read in the increasing order of their priorities, then
+// their names.
+//
+// If watch is true, it also watches the directory. Files added to the
+// directory later are read, changed files are reloaded, and values of removed
+// files are removed. Keys of a removed file fall back to their values in the
+// other files with lower priorities.
+//
+// Options apply to every file, including files added later (see ReadOption).
+func (c *Config) ReadDir(dir string, pattern string, watch bool, opts ...ReadOption) error {
+	var o = newReadOptions(opts)
+	watch = o.getWatch(watch)
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return FormatError.Newf("invalid pattern %s (%v)", pattern, err)
+	}
+
+	dir = filepath.Clean(dir)
+	if watch {
+		if err := c.watchDir(dir, watchedDir{pattern: pattern, options: o}); err != nil {
+			return err
+		}
+	}
+
+	var entries, err = ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) && watch {
+			return nil
+		}
+		return ConfigError.New(err)
+	}
+
+	var filenames []string
+	for _, entry := range entries {
+		var filename = filepath.Join(dir, entry.Name())
+		if !entry.IsDir() && isDirFile(filename, pattern, o.format) {
+			filenames = append(filenames, filename)
+		}
+	}
+
+	sortByPriority(filenames)
+	for _, filename := range filenames {
+		c.setSourceFormat(filename, o.format)
+		var priority = o.getPriority(getPriority(filename))
+		if err := c.readFile(filename, priority, watch, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// watchedDir is a directory watched by ReadDir.
+type watchedDir struct {
+	pattern string
+	options readOptions
+}
+
+// watchDir adds the directory to watcher, so files added to the directory
+// later are read.
+func (c *Config) watchDir(dir string, wd watchedDir) error {
+	var watcher = c.lock.RLockFunc(func() any {
+		return c.watcher
+	}).(*fsnotify.Watcher)
+
+	if watcher == nil {
+		if err := c.initWatcher(); err != nil {
+			return err
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return ConfigError.New(err)
+	}
+
+	if !c.isDirWatched(dir) {
+		if err := c.watcher.Add(dir); err != nil {
+			return ConfigError.New(err)
+		}
+	}
+
+	c.watchedDirs[dir] = wd
+	return nil
+}
+
+// unwatchDir stops watching the directory and all files in it. This method is
+// not thread-safe, the caller must hold the lock.
+func (c *Config) unwatchDir(dir string) error {
+	delete(c.watchedDirs, dir)
+	for name := range c.watchedFiles {
+		if filepath.Dir(name) == dir {
+			delete(c.watchedFiles, name)
+		}
+	}
+
+	if !c.isDirWatched(dir) {
+		if err := c.watcher.Remove(dir); err != nil {
+			return ConfigError.New(err)
+		}
+	}
+
+	return nil
+}
+
+// isDirFile returns true if the file has a supported extension or the format
+// is given, and its name matches the pattern of ReadDir.
+func isDirFile(filename, pattern string, format Format) bool {
+	if format == UnknownFormat && getFormat(filename) == UnknownFormat {
+		return false
+	}
+
+	var ok, _ = filepath.Match(pattern, filepath.Base(filename))
+	return ok
+}
+
+// sortByPriority sorts filenames in the increasing order of their priorities,
+// then their names. Files with higher priority are read later.
+func sortByPriority(filenames []string) {
+	sort.SliceStable(filenames, func(i, j int) bool {
+		var pi, pj = getPriority(filenames[i]), getPriority(filenames[j])
+		if pi != pj {
+			return pi < pj
+		}
+		return filenames[i] < filenames[j]
+	})
+}
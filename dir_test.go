@@ -0,0 +1,136 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+func TestConfigReadDir(t *testing.T) {
+	var dir = t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "20-override.json"), []byte(`{"foo": "buzz"}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "10-default.json"), []byte(`{"foo": "bar", "bizz": 1}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "30-ignored.ini"), []byte("foo = bemm"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("# readme"), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.ReadDir(dir, "*.json", false)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "buzz").Test(t)
+	xycond.ExpectEqual(cfg.MustGet("bizz").MustInt(), 1).Test(t)
+}
+
+func TestConfigReadDirAllFormats(t *testing.T) {
+	var dir = t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "10-default.json"), []byte(`{"general": {"foo": "bar"}}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "20-override.ini"), []byte("[general]\nfoo = buzz"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("# readme"), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.ReadDir(dir, "", false)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("general.foo").MustString(), "buzz").Test(t)
+}
+
+func TestConfigReadDirNotExist(t *testing.T) {
+	var cfg = xyconfig.GetConfig(t.Name())
+	var err = cfg.ReadDir(filepath.Join(t.TempDir(), "conf.d"), "", false)
+	xycond.ExpectError(err, xyconfig.ConfigError).Test(t)
+}
+
+func TestConfigReadDirInvalidPattern(t *testing.T) {
+	var cfg = xyconfig.GetConfig(t.Name())
+	var err = cfg.ReadDir(t.TempDir(), "[", false)
+	xycond.ExpectError(err, xyconfig.FormatError).Test(t)
+}
+
+func TestConfigReadDirWithWatching(t *testing.T) {
+	var dir = filepath.Join(t.TempDir(), "conf.d")
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadDir(dir, "*.json", true)).Test(t)
+
+	ioutil.WriteFile(filepath.Join(dir, "10-default.json"), []byte(`{"foo": "bar"}`), 0644)
+	xycond.ExpectTrue(eventually(func() bool {
+		var v, ok = cfg.Get("foo")
+		return ok && v.MustString() == "bar"
+	})).Test(t)
+
+	ioutil.WriteFile(filepath.Join(dir, "20-override.json"), []byte(`{"foo": "buzz"}`), 0644)
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustString() == "buzz"
+	})).Test(t)
+
+	ioutil.WriteFile(filepath.Join(dir, "20-override.json"), []byte(`{"foo": "bizz"}`), 0644)
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustString() == "bizz"
+	})).Test(t)
+
+	os.Remove(filepath.Join(dir, "10-default.json"))
+	os.Remove(filepath.Join(dir, "20-override.json"))
+	xycond.ExpectTrue(eventually(func() bool {
+		var _, ok = cfg.Get("foo")
+		return !ok
+	})).Test(t)
+}
+
+func TestConfigReadDirRemoveFallback(t *testing.T) {
+	var dir = t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "10-base.json"), []byte(`{"a": "base"}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "20-local.json"), []byte(`{"a": "local"}`), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadDir(dir, "*.json", true)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("a").MustString(), "local").Test(t)
+
+	os.Remove(filepath.Join(dir, "20-local.json"))
+	xycond.ExpectTrue(eventually(func() bool {
+		var v, ok = cfg.Get("a")
+		return ok && v.MustString() == "base"
+	})).Test(t)
+
+	// A new override is applied over the base file again.
+	ioutil.WriteFile(filepath.Join(dir, "30-new.json"), []byte(`{"a": "new"}`), 0644)
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("a").MustString() == "new"
+	})).Test(t)
+}
+
+func TestConfigReadDirUnWatch(t *testing.T) {
+	var dir = t.TempDir()
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadDir(dir, "*.json", true)).Test(t)
+	xycond.ExpectNil(cfg.UnWatch(dir)).Test(t)
+
+	ioutil.WriteFile(filepath.Join(dir, "10-default.json"), []byte(`{"foo": "bar"}`), 0644)
+	time.Sleep(100 * time.Millisecond)
+	var _, ok = cfg.Get("foo")
+	xycond.ExpectFalse(ok).Test(t)
+}
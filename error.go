@@ -33,3 +33,6 @@ var FormatError = ConfigError.NewException("FormatError")
 
 // ConfigKeyError happens when a key doesn't exist in Config.
 var ConfigKeyError = xyerror.Combine(ConfigError, xyerror.KeyError).NewException("ConfigKeyError")
+
+// CryptoError happens when a value cannot be encrypted or decrypted.
+var CryptoError = ConfigError.NewException("CryptoError")
@@ -0,0 +1,170 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"flag"
+	"strings"
+)
+
+// flagPriority is the priority of flags read by ParseFlags. It is higher than
+// the priority of environment variables.
+const flagPriority = maxPriority + 1
+
+// configFlag is the name of the flag listing config files in ParseFlags.
+const configFlag = "config"
+
+// ReadFlags reads the flags which are explicitly set in the FlagSet. The name
+// of a flag is the dot-separated key, for example -general.timeout=5s. Values
+// are strings read non-strictly, and encrypted values are decrypted, as ENV
+// does.
+//
+// The FlagSet must be parsed before calling this method.
+func (c *Config) ReadFlags(fs *flag.FlagSet, priority int) error {
+	if !fs.Parsed() {
+		return ConfigError.Newf("the flag set %s is not parsed", fs.Name())
+	}
+
+	var d = c.newDecrypter()
+	var values = make(map[string]any)
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == configFlag || err != nil {
+			return
+		}
+		values[f.Name], err = d.decryptValue(f.Value.String())
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for k, v := range values {
+		c.set(k, Value{value: v, priority: priority, strict: false, source: "flags"})
+	}
+
+	return nil
+}
+
+// RegisterFlags defines a flag in the FlagSet for every key of the Config,
+// whose default value is the current value (sensitive values are redacted).
+// Boolean values define boolean flags. Keys which are already defined in the
+// FlagSet are skipped.
+//
+// Read the defaults of the Config before calling this method, for example by
+// ReadFS with embedded files.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	var values = make(map[string]any)
+	flattenAny("", c.ToMap(), values)
+
+	for _, key := range sortedKeys(values) {
+		if fs.Lookup(key) != nil {
+			continue
+		}
+
+		var usage = "config value of " + key
+		if b, ok := values[key].(bool); ok {
+			fs.Bool(key, b, usage)
+		} else {
+			fs.String(key, formatValue(values[key]), usage)
+		}
+	}
+}
+
+// ParseFlags parses the arguments with the FlagSet, reads the files listed by
+// the -config flag in their order, then reads the explicitly set flags on top
+// of them with the priority 101 (higher than environment variables). The
+// -config flag can be repeated or contain a comma-separated list, and it
+// accepts any instance supported by Read.
+//
+// It registers flags of the current keys by RegisterFlags before parsing. If
+// the FlagSet already defines the -config flag, it must be a string flag or a
+// flag defined by an earlier ParseFlags.
+//
+// For example:
+//    var config = xyconfig.GetConfig("app")
+//    config.ReadFS(defaults, "*.json")
+//    config.ParseFlags(flag.CommandLine, os.Args[1:])
+//
+//    $ app -config=10-app.json,20-local.ini -general.timeout=5s
+func (c *Config) ParseFlags(fs *flag.FlagSet, args []string) error {
+	var configValue flag.Value = &flagList{}
+	if f := fs.Lookup(configFlag); f != nil {
+		configValue = f.Value
+	} else {
+		fs.Var(configValue, configFlag, "config files to read, in increasing priority")
+	}
+	c.RegisterFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return ConfigError.New(err)
+	}
+
+	files, err := configFiles(configValue)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := c.Read(file); err != nil {
+			return err
+		}
+	}
+
+	return c.ReadFlags(fs, flagPriority)
+}
+
+// configFiles returns the files listed by the value of the -config flag.
+func configFiles(value flag.Value) (flagList, error) {
+	if files, ok := value.(*flagList); ok {
+		return *files, nil
+	}
+
+	var getter, ok = value.(flag.Getter)
+	if ok {
+		if s, ok := getter.Get().(string); ok {
+			var files flagList
+			files.Set(s)
+			return files, nil
+		}
+	}
+
+	return nil, ConfigError.Newf("the -%s flag is not a string flag", configFlag)
+}
+
+// flagList is a flag containing a list of comma-separated strings, it can be
+// repeated.
+type flagList []string
+
+// String returns the comma-separated list.
+func (l *flagList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set appends comma-separated values to the list.
+func (l *flagList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+func TestConfigReadFlags(t *testing.T) {
+	var fs = flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	fs.String("general.timeout", "1s", "")
+	fs.Int("retries", 3, "")
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectError(cfg.ReadFlags(fs, 0), xyconfig.ConfigError).Test(t)
+
+	xycond.ExpectNil(fs.Parse([]string{"-general.timeout=5s"})).Test(t)
+	xycond.ExpectNil(cfg.ReadFlags(fs, 0)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("general.timeout").MustDuration(), 5*time.Second).Test(t)
+
+	// Flags which are not set are not read.
+	var _, ok = cfg.Get("retries")
+	xycond.ExpectFalse(ok).Test(t)
+}
+
+func TestConfigRegisterFlags(t *testing.T) {
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.ReadJSON(0, []byte(`{"general": {"timeout": "1s", "debug": false}, "password": "secret"}`))
+	cfg.AddSensitive("password")
+
+	var fs = flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	fs.String("password", "", "")
+	cfg.RegisterFlags(fs)
+
+	xycond.ExpectEqual(fs.Lookup("general.timeout").DefValue, "1s").Test(t)
+	xycond.ExpectEqual(fs.Lookup("general.debug").DefValue, "false").Test(t)
+	xycond.ExpectEqual(fs.Lookup("password").DefValue, "").Test(t)
+
+	xycond.ExpectNil(fs.Parse([]string{"-general.debug"})).Test(t)
+	xycond.ExpectNil(cfg.ReadFlags(fs, 0)).Test(t)
+	xycond.ExpectTrue(cfg.MustGet("general.debug").MustBool()).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("general.timeout").MustString(), "1s").Test(t)
+}
+
+func TestConfigParseFlags(t *testing.T) {
+	var dir = t.TempDir()
+	var app = filepath.Join(dir, "10-app.json")
+	var local = filepath.Join(dir, "20-local.ini")
+	ioutil.WriteFile(app, []byte(`{"general": {"timeout": "2s", "retries": 1}, "name": "app"}`), 0644)
+	ioutil.WriteFile(local, []byte("[general]\nretries = 2"), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetWatchInterval(0)
+	cfg.ReadJSON(0, []byte(`{"general": {"timeout": "1s"}}`))
+
+	var fs = flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	var err = cfg.ParseFlags(fs, []string{
+		"-config", app, "-config=" + local, "-general.timeout=5s",
+	})
+	xycond.ExpectNil(err).Test(t)
+
+	xycond.ExpectEqual(cfg.MustGet("general.timeout").MustDuration(), 5*time.Second).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("general.retries").MustInt(), 2).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("name").MustString(), "app").Test(t)
+
+	// Flags have a higher priority than environment variables.
+	cfg.Set("general.timeout", "1m", 100, true)
+	xycond.ExpectEqual(cfg.MustGet("general.timeout").MustDuration(), 5*time.Second).Test(t)
+	var _, ok = cfg.Get("config")
+	xycond.ExpectFalse(ok).Test(t)
+}
+
+func TestConfigParseFlagsExistingConfig(t *testing.T) {
+	var dir = t.TempDir()
+	var app = filepath.Join(dir, "10-app.json")
+	var local = filepath.Join(dir, "20-local.ini")
+	ioutil.WriteFile(app, []byte(`{"name": "app", "general": {"retries": 1}}`), 0644)
+	ioutil.WriteFile(local, []byte("[general]\nretries = 2"), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetWatchInterval(0)
+
+	var fs = flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	fs.String("config", "", "config files")
+	xycond.ExpectNil(cfg.ParseFlags(fs, []string{"-config", app + "," + local})).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("name").MustString(), "app").Test(t)
+	xycond.ExpectEqual(cfg.MustGet("general.retries").MustInt(), 2).Test(t)
+
+	// The -config flag must list files.
+	fs = flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	fs.Bool("config", false, "config files")
+	var err = cfg.ParseFlags(fs, []string{"-config"})
+	xycond.ExpectError(err, xyconfig.ConfigError).Test(t)
+}
+
+func TestConfigParseFlagsError(t *testing.T) {
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetWatchInterval(0)
+
+	var fs = flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	var err = cfg.ParseFlags(fs, []string{"-unknown=1"})
+	xycond.ExpectError(err, xyconfig.ConfigError).Test(t)
+
+	fs = flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	err = cfg.ParseFlags(fs, []string{"-config=a.unknown, "})
+	xycond.ExpectError(err, xyconfig.ConfigError).Test(t)
+}
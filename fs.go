@@ -0,0 +1,73 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"io/fs"
+)
+
+// ReadFS reads all files in the file system whose names match the pattern
+// (see fs.Glob), for example the files embedded by //go:embed. Files with
+// unsupported extensions are skipped. As ReadFile, the format is chosen by the
+// extension and the priority is extracted from the file name. Files are read
+// in the increasing order of their priorities, then their names. Options
+// override the format and the priority of all files (see ReadOption).
+//
+// Include directives are ignored, as ReadBytes does. It returns ConfigError if
+// no file matches the pattern.
+func (c *Config) ReadFS(fsys fs.FS, pattern string, opts ...ReadOption) error {
+	var o = newReadOptions(opts)
+	var matches, err = fs.Glob(fsys, pattern)
+	if err != nil {
+		return FormatError.Newf("invalid pattern %s (%v)", pattern, err)
+	}
+
+	var names []string
+	for _, name := range matches {
+		if o.format != UnknownFormat || getFormat(name) != UnknownFormat {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return ConfigError.Newf("not found any file matching %s", pattern)
+	}
+
+	sortByPriority(names)
+	for _, name := range names {
+		var data, err = fs.ReadFile(fsys, name)
+		if err != nil {
+			return ConfigError.New(err)
+		}
+
+		var format = o.format
+		if format == UnknownFormat {
+			format = getFormat(name)
+		}
+
+		var priority = o.getPriority(getPriority(name))
+		if err := c.readBytes(format, priority, data, "fs:"+name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+func TestConfigReadFS(t *testing.T) {
+	var fsys = fstest.MapFS{
+		"config/10-app.json": {Data: []byte(`{"db": {"host": "localhost", "port": 8080}}`)},
+		"config/20-app.ini":  {Data: []byte("[db]\nhost = embedded.local")},
+		"config/30-app.env":  {Data: []byte("debug=true")},
+		"config/README.md":   {Data: []byte("# defaults")},
+	}
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.ReadFS(fsys, "config/*")).Test(t)
+
+	xycond.ExpectEqual(cfg.MustGet("db.host").MustString(), "embedded.local").Test(t)
+	xycond.ExpectEqual(cfg.MustGet("db.port").MustInt(), 8080).Test(t)
+	xycond.ExpectTrue(cfg.MustGet("debug").MustBool()).Test(t)
+}
+
+func TestConfigReadFSWithOverride(t *testing.T) {
+	var fsys = fstest.MapFS{
+		"10-app.json": {Data: []byte(`{"host": "localhost", "port": 8080}`)},
+	}
+
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"20-app.json": `{"host": "example.com"}`,
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.ReadFile(filepath.Join(dir, "20-app.json"), false)).Test(t)
+	xycond.ExpectNil(cfg.ReadFS(fsys, "*.json")).Test(t)
+
+	// The on-disk file has the higher priority, so the embedded defaults can
+	// not override it.
+	xycond.ExpectEqual(cfg.MustGet("host").MustString(), "example.com").Test(t)
+	xycond.ExpectEqual(cfg.MustGet("port").MustInt(), 8080).Test(t)
+}
+
+func TestConfigReadFSError(t *testing.T) {
+	var fsys = fstest.MapFS{
+		"app.json":    {Data: []byte(`{"host": "localhost"}`)},
+		"invalid.ini": {Data: []byte("[")},
+	}
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectError(cfg.ReadFS(fsys, "["), xyconfig.FormatError).Test(t)
+	xycond.ExpectError(cfg.ReadFS(fsys, "*.yaml"), xyconfig.ConfigError).Test(t)
+	xycond.ExpectNotNil(cfg.ReadFS(fsys, "*.ini")).Test(t)
+}
@@ -7,6 +7,7 @@ require (
 	github.com/fsnotify/fsnotify v1.6.0
 	github.com/go-ini/ini v1.67.0
 	github.com/joho/godotenv v1.4.0
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/xybor-x/xycond v1.0.0
 	github.com/xybor-x/xyerror v1.0.5
 	github.com/xybor-x/xylock v0.0.1
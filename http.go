@@ -0,0 +1,205 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+)
+
+// defaultHTTPTimeout is the timeout of HTTP requests if no client is set.
+const defaultHTTPTimeout = 30 * time.Second
+
+// contentTypes maps media types to their formats.
+var contentTypes = map[string]Format{
+	"application/json":  JSON,
+	"text/json":         JSON,
+	"application/x-ini": INI,
+	"text/x-ini":        INI,
+	"text/ini":          INI,
+	"application/x-env": ENV,
+	"text/x-env":        ENV,
+}
+
+// HTTPOptions contains the options to request HTTP sources.
+type HTTPOptions struct {
+	// Header contains the headers added to every request, such as the
+	// Authorization header.
+	Header http.Header
+
+	// TLSConfig is the TLS configuration of HTTPS requests. It is ignored if
+	// Client is set.
+	TLSConfig *tls.Config
+
+	// Timeout is the time limit of a request. The default timeout is 30
+	// seconds. It is ignored if Client is set.
+	Timeout time.Duration
+
+	// Client is the client to send requests.
+	Client *http.Client
+}
+
+// SetHTTPOptions sets the options to request HTTP sources.
+func (c *Config) SetHTTPOptions(opts HTTPOptions) {
+	var client = opts.Client
+	if client == nil {
+		var timeout = opts.Timeout
+		if timeout == 0 {
+			timeout = defaultHTTPTimeout
+		}
+
+		var transport = http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = opts.TLSConfig
+		client = &http.Client{Timeout: timeout, Transport: transport}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.httpClient = client
+	c.httpHeader = opts.Header.Clone()
+}
+
+// ReadHTTP reads a file from a HTTP(S) url and watch for their changes every
+// duration. Set the duration as zero if no need to watch the change.
+//
+// The format is detected from the extension of the url path, or from the
+// Content-Type of the response if the extension is unknown, or from the content
+// otherwise. The priority is extracted from the file name as ReadFile does.
+//
+// The url is requested with the ETag and Last-Modified of the last read, so an
+// unchanged file is not parsed again. Failed requests are handled as ReadS3
+// does. Options override the format, the priority and the interval (see
+// ReadOption).
+func (c *Config) ReadHTTP(url string, d time.Duration, opts ...ReadOption) error {
+	var o = newReadOptions(opts)
+	c.setSourceFormat(url, o.format)
+	return c.readHTTP(url, o.getPriority(getURLPriority(url)), o.getInterval(d))
+}
+
+// readHTTP reads a file from a HTTP(S) url with the priority.
+func (c *Config) readHTTP(url string, priority int, d time.Duration) error {
+	var u, err = neturl.Parse(url)
+	if err != nil {
+		return FormatError.Newf("can not parse the url %s (%v)", url, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return FormatError.Newf("unsupported scheme %s", u.Scheme)
+	}
+
+	return c.readRemote(url, priority, d,
+		func(version remoteVersion) (remoteObject, error) {
+			return c.getHTTPObject(url, c.getSourceFormat(url, u.Path), version)
+		})
+}
+
+// getHTTPObject requests the url if it has changed since the version. If the
+// format is unknown, it is chosen by the Content-Type of the response.
+func (c *Config) getHTTPObject(
+	url string, format Format, version remoteVersion,
+) (remoteObject, error) {
+	var req, err = http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return remoteObject{}, ConfigError.New(err)
+	}
+
+	c.lock.RLock()
+	var client = c.httpClient
+	for key, values := range c.httpHeader {
+		req.Header[key] = values
+	}
+	c.lock.RUnlock()
+
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+
+	if version.etag != "" {
+		req.Header.Set("If-None-Match", version.etag)
+	}
+	if version.lastModified != "" {
+		req.Header.Set("If-Modified-Since", version.lastModified)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return remoteObject{}, ConfigError.New(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return remoteObject{}, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return remoteObject{}, ConfigError.Newf(
+			"unexpected status %s of %s", resp.Status, url)
+	}
+
+	if format == UnknownFormat {
+		format = getContentFormat(resp.Header.Get("Content-Type"))
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return remoteObject{}, ConfigError.New(err)
+	}
+
+	return remoteObject{
+		data:   data,
+		format: format,
+		version: remoteVersion{
+			etag:         resp.Header.Get("ETag"),
+			lastModified: resp.Header.Get("Last-Modified"),
+		},
+	}, nil
+}
+
+// getURLPriority extracts the priority from the file name of the url path.
+func getURLPriority(url string) int {
+	if u, err := neturl.Parse(url); err == nil {
+		return getPriority(u.Path)
+	}
+	return 0
+}
+
+// getContentFormat returns the format corresponding to the content type.
+func getContentFormat(contentType string) Format {
+	var mediaType, _, err = mime.ParseMediaType(contentType)
+	if err != nil {
+		return UnknownFormat
+	}
+
+	if format, ok := contentTypes[mediaType]; ok {
+		return format
+	}
+
+	if strings.HasSuffix(mediaType, "+json") {
+		return JSON
+	}
+
+	return UnknownFormat
+}
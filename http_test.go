@@ -0,0 +1,177 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+// httpSource is a HTTP server serving a config file with an ETag.
+type httpSource struct {
+	lock        sync.Mutex
+	content     string
+	etag        string
+	contentType string
+	requests    int32
+	notModified int32
+}
+
+func (s *httpSource) set(content, etag string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.content = content
+	s.etag = etag
+}
+
+func (s *httpSource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	atomic.AddInt32(&s.requests, 1)
+	if r.Header.Get("If-None-Match") == s.etag {
+		atomic.AddInt32(&s.notModified, 1)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if s.contentType != "" {
+		w.Header().Set("Content-Type", s.contentType)
+	}
+	w.Header().Set("ETag", s.etag)
+	w.Write([]byte(s.content))
+}
+
+func TestConfigReadHTTP(t *testing.T) {
+	var source = &httpSource{content: `{"foo": "bar"}`, etag: `"1"`}
+	var server = httptest.NewServer(source)
+	defer server.Close()
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.ReadHTTP(server.URL+"/20-app.json?v=1", 0)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+
+	// The file has the priority 20 from its name.
+	cfg.Set("foo", "low", 10, true)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+
+	xycond.ExpectNil(cfg.ReadHTTP(server.URL+"/20-app.json?v=1", 0)).Test(t)
+	xycond.ExpectEqual(atomic.LoadInt32(&source.notModified), int32(1)).Test(t)
+}
+
+func TestConfigReadHTTPContentType(t *testing.T) {
+	var source = &httpSource{
+		content:     "[general]\nfoo = bar",
+		etag:        `"1"`,
+		contentType: "text/x-ini; charset=utf-8",
+	}
+	var server = httptest.NewServer(source)
+	defer server.Close()
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.Read(server.URL + "/config")).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("general.foo").MustString(), "bar").Test(t)
+	cfg.CloseWatcher()
+
+	source.lock.Lock()
+	source.content = "<html></html>"
+	source.contentType = "text/html"
+	source.lock.Unlock()
+	var err = cfg.ReadHTTP(server.URL+"/other", 0)
+	xycond.ExpectError(err, xyconfig.FormatError).Test(t)
+}
+
+func TestConfigReadHTTPWithChange(t *testing.T) {
+	var source = &httpSource{content: `{"foo": "bar"}`, etag: `"1"`}
+	var server = httptest.NewServer(source)
+	defer server.Close()
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadHTTP(server.URL+"/app.json", 10*time.Millisecond)).Test(t)
+
+	source.set(`{"foo": "buzz"}`, `"2"`)
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustString() == "buzz"
+	})).Test(t)
+}
+
+func TestConfigReadHTTPOptions(t *testing.T) {
+	var server = httptest.NewTLSServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("foo=bar"))
+		}))
+	defer server.Close()
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	var err = cfg.ReadHTTP(server.URL+"/app.env", 0)
+	xycond.ExpectError(err, xyconfig.ConfigError).Test(t)
+
+	cfg.SetHTTPOptions(xyconfig.HTTPOptions{
+		Header: http.Header{"Authorization": []string{"Bearer token"}},
+		Client: server.Client(),
+	})
+	xycond.ExpectNil(cfg.ReadHTTP(server.URL+"/app.env", 0)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+
+	cfg.SetHTTPOptions(xyconfig.HTTPOptions{
+		TLSConfig: server.Client().Transport.(*http.Transport).TLSClientConfig,
+	})
+	err = cfg.ReadHTTP(server.URL+"/app.env", 0)
+	xycond.ExpectError(err, xyconfig.ConfigError).Test(t)
+}
+
+func TestConfigReadHTTPInvalid(t *testing.T) {
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectError(cfg.ReadHTTP("ftp://host/app.json", 0), xyconfig.FormatError).Test(t)
+	xycond.ExpectError(cfg.ReadHTTP("http://%zz/app.json", 0), xyconfig.FormatError).Test(t)
+}
+
+func TestConfigReadHTTPWithCache(t *testing.T) {
+	var dir = t.TempDir()
+	var source = &httpSource{
+		content:     `{"foo": "bar"}`,
+		etag:        `"1"`,
+		contentType: "application/json",
+	}
+	var server = httptest.NewServer(source)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetCacheDir(dir)
+	xycond.ExpectNil(cfg.ReadHTTP(server.URL+"/config", 0)).Test(t)
+	server.Close()
+
+	var other = xyconfig.GetConfig(t.Name() + "-other")
+	other.SetCacheDir(dir)
+	xycond.ExpectNil(other.ReadHTTP(server.URL+"/config", 0)).Test(t)
+	xycond.ExpectEqual(other.MustGet("foo").MustString(), "bar").Test(t)
+}
@@ -0,0 +1,218 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+// Keys of include directives.
+const (
+	jsonIncludeKey = "$include"
+	iniIncludeKey  = "include"
+	envIncludeKey  = "#include"
+)
+
+// readIncludes reads all files included by the file. An included file is read
+// with the priority in its name, or inherits the priority of the including
+// file if its name has no priority.
+func (c *Config) readIncludes(
+	filename string, format Format, priority int, data []byte, watch bool, stack []string,
+) error {
+	var patterns, err = parseIncludes(format, data)
+	if err != nil {
+		return err
+	}
+	c.clearIncludes(filename)
+
+	var includes []string
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(filename), pattern)
+		}
+
+		if !strings.ContainsAny(pattern, "*?[") {
+			includes = append(includes, pattern)
+			continue
+		}
+
+		var matches, err = filepath.Glob(pattern)
+		if err != nil {
+			return FormatError.Newf("invalid include pattern %s (%v)", pattern, err)
+		}
+
+		var files []string
+		for _, match := range matches {
+			if getFormat(match) != UnknownFormat {
+				files = append(files, match)
+			}
+		}
+		sortByPriority(files)
+		includes = append(includes, files...)
+	}
+
+	for _, include := range includes {
+		var p = priority
+		if hasPriority(include) {
+			p = getPriority(include)
+		}
+
+		c.addInclude(filename, include)
+		if err := c.readFile(include, p, watch, stack); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addInclude records that the file includes another file.
+func (c *Config) addInclude(filename, include string) {
+	c.lock.WLockFunc(func() {
+		var name = filepath.Clean(include)
+		if c.includedBy[name] == nil {
+			c.includedBy[name] = make(map[string]bool)
+		}
+		c.includedBy[name][filepath.Clean(filename)] = true
+	})
+}
+
+// clearIncludes forgets the files included by the file, before its include
+// directives are read again.
+func (c *Config) clearIncludes(filename string) {
+	c.lock.WLockFunc(func() {
+		var name = filepath.Clean(filename)
+		for include, parents := range c.includedBy {
+			delete(parents, name)
+			if len(parents) == 0 {
+				delete(c.includedBy, include)
+			}
+		}
+	})
+}
+
+// includeRoots returns the files which include the file, directly or through
+// other files, and are not included by any file.
+func (c *Config) includeRoots(filename string) []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var start = filepath.Clean(filename)
+	var roots []string
+	var visited = make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+
+		if len(c.includedBy[name]) == 0 {
+			if name != start {
+				roots = append(roots, name)
+			}
+			return
+		}
+
+		for parent := range c.includedBy[name] {
+			visit(parent)
+		}
+	}
+	visit(start)
+
+	sort.Strings(roots)
+	return roots
+}
+
+// parseIncludes returns the paths in include directives of data.
+func parseIncludes(format Format, data []byte) ([]string, error) {
+	switch format {
+	case JSON:
+		var m map[string]any
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, parseError(JSON, data, err)
+		}
+
+		switch t := m[jsonIncludeKey].(type) {
+		case nil:
+			return nil, nil
+		case string:
+			return []string{t}, nil
+		case []any:
+			var includes []string
+			for _, e := range t {
+				var s, ok = e.(string)
+				if !ok {
+					return nil, FormatError.Newf("%s must contain strings, got a %T", jsonIncludeKey, e)
+				}
+				includes = append(includes, s)
+			}
+			return includes, nil
+		default:
+			return nil, FormatError.Newf("%s must be a string or an array, got a %T", jsonIncludeKey, t)
+		}
+
+	case INI:
+		var cfg, err = ini.Load(data)
+		if err != nil {
+			return nil, parseError(INI, data, err)
+		}
+
+		var section = cfg.Section(ini.DefaultSection)
+		if !section.HasKey(iniIncludeKey) {
+			return nil, nil
+		}
+		return splitList(section.Key(iniIncludeKey).Value()), nil
+
+	case ENV:
+		var includes []string
+		var scanner = bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			var line = strings.TrimSpace(scanner.Text())
+			if strings.HasPrefix(line, envIncludeKey+" ") {
+				var path = strings.TrimSpace(strings.TrimPrefix(line, envIncludeKey))
+				includes = append(includes, strings.Trim(path, `"'`))
+			}
+		}
+		return includes, nil
+
+	default:
+		return nil, FormatError.New("unsupported format")
+	}
+}
+
+// splitList splits a comma-separated list and trims its elements.
+func splitList(s string) []string {
+	var result []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			result = append(result, e)
+		}
+	}
+	return result
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		var filename = filepath.Join(dir, name)
+		xycond.ExpectNil(os.MkdirAll(filepath.Dir(filename), 0755)).Test(t)
+		xycond.ExpectNil(ioutil.WriteFile(filename, []byte(content), 0644)).Test(t)
+	}
+}
+
+func TestConfigReadFileIncludeJSON(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.json":           `{"$include": ["common.json", "db/*.json"], "name": "app", "timeout": 2}`,
+		"common.json":        `{"name": "common", "timeout": 1, "debug": true}`,
+		"db/10-primary.json": `{"db": {"host": "primary", "port": 5432}}`,
+		"db/20-replica.json": `{"db": {"host": "replica"}}`,
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.ReadFile(filepath.Join(dir, "app.json"), false)).Test(t)
+
+	xycond.ExpectEqual(cfg.MustGet("name").MustString(), "app").Test(t)
+	xycond.ExpectEqual(cfg.MustGet("timeout").MustInt(), 2).Test(t)
+	xycond.ExpectTrue(cfg.MustGet("debug").MustBool()).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("db.host").MustString(), "replica").Test(t)
+	xycond.ExpectEqual(cfg.MustGet("db.port").MustInt(), 5432).Test(t)
+
+	var _, ok = cfg.Get("$include")
+	xycond.ExpectFalse(ok).Test(t)
+}
+
+func TestConfigReadFileIncludeINI(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.ini":    "include = common.ini\n[general]\ntimeout = 2",
+		"common.ini": "[general]\ntimeout = 1\ndebug = true",
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.ReadFile(filepath.Join(dir, "app.ini"), false)).Test(t)
+
+	xycond.ExpectEqual(cfg.MustGet("general.timeout").MustInt(), 2).Test(t)
+	xycond.ExpectTrue(cfg.MustGet("general.debug").MustBool()).Test(t)
+
+	var _, ok = cfg.Get("include")
+	xycond.ExpectFalse(ok).Test(t)
+}
+
+func TestConfigReadFileIncludeENV(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.env":        "#include sub/common.env\ntimeout=2",
+		"sub/common.env": "timeout=1\ndebug=true",
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.ReadFile(filepath.Join(dir, "app.env"), false)).Test(t)
+
+	xycond.ExpectEqual(cfg.MustGet("timeout").MustInt(), 2).Test(t)
+	xycond.ExpectTrue(cfg.MustGet("debug").MustBool()).Test(t)
+}
+
+func TestConfigReadFileIncludePriority(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"10-app.json":  `{"$include": ["inherit.json", "90-high.json"], "foo": "app", "bar": "app"}`,
+		"inherit.json": `{"foo": "inherit"}`,
+		"90-high.json": `{"bar": "high"}`,
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.ReadFile(filepath.Join(dir, "10-app.json"), false)).Test(t)
+
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "app").Test(t)
+	xycond.ExpectEqual(cfg.MustGet("bar").MustString(), "high").Test(t)
+
+	// The included file inherits the priority 10, so it can not override a
+	// value with priority 20.
+	cfg.Set("foo", "override", 20, true)
+	xycond.ExpectNil(cfg.ReadFile(filepath.Join(dir, "10-app.json"), false)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "override").Test(t)
+}
+
+func TestConfigReadFileIncludeCycle(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.json": `{"$include": "b.json"}`,
+		"b.json": `{"$include": "./a.json"}`,
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	var err = cfg.ReadFile(filepath.Join(dir, "a.json"), false)
+	xycond.ExpectError(err, xyconfig.FormatError).Test(t)
+}
+
+func TestConfigReadFileIncludeInvalid(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.json": `{"$include": 1}`,
+		"b.json": `{"$include": ["not-exist.json"]}`,
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectError(cfg.ReadFile(filepath.Join(dir, "a.json"), false), xyconfig.FormatError).Test(t)
+	xycond.ExpectError(cfg.ReadFile(filepath.Join(dir, "b.json"), false), xyconfig.ConfigError).Test(t)
+}
+
+func TestConfigReadFileIncludeWithWatching(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"10-app.json": `{"$include": "common.json"}`,
+		"common.json": `{"foo": "bar"}`,
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadFile(filepath.Join(dir, "10-app.json"), true)).Test(t)
+	cfg.Set("foo", "low", 5, true)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+
+	writeFiles(t, dir, map[string]string{"common.json": `{"foo": "buzz"}`})
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustString() == "buzz"
+	})).Test(t)
+}
+
+func TestConfigReadFileIncludeChangeKeepsParent(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.json":   `{"$include": "common.json", "a": "base"}`,
+		"common.json": `{"a": "common", "b": "common"}`,
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadFile(filepath.Join(dir, "base.json"), true)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("a").MustString(), "base").Test(t)
+
+	writeFiles(t, dir, map[string]string{"common.json": `{"a": "changed", "b": "changed"}`})
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("b").MustString() == "changed"
+	})).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("a").MustString(), "base").Test(t)
+}
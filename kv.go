@@ -0,0 +1,189 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"context"
+	"strings"
+)
+
+// KVPair is a key-value pair of a KVStore.
+type KVPair struct {
+	Key   string
+	Value []byte
+}
+
+// KVStore is a hierarchical key-value store, such as etcd or Consul. The
+// package kvstore contains implementations of this interface.
+type KVStore interface {
+	// List returns all pairs whose keys have the prefix and the current
+	// revision of the store.
+	List(ctx context.Context, prefix string) ([]KVPair, uint64, error)
+
+	// Wait blocks until a key with the prefix may have changed after the
+	// revision, then it returns the new revision. Returning early is allowed,
+	// the pairs are listed and compared again.
+	Wait(ctx context.Context, prefix string, revision uint64) (uint64, error)
+}
+
+// ReadKV reads all pairs under the prefix of a key-value store. The keys are
+// relative to the prefix, slashes are replaced with dots, for example the key
+// /services/app/general/timeout under the prefix /services/app/ is
+// general.timeout. The values are strings read non-strictly, as ENV does.
+//
+// If watch is true, it waits for changes of the store and applies them, values
+// of deleted keys are removed. Failures are retried with an exponential backoff.
+// The source is named "kv:" followed by the prefix, use this name with Status
+// and UnWatch.
+func (c *Config) ReadKV(store KVStore, prefix string, priority int, watch bool) error {
+	var source = "kv:" + prefix
+	if !watch {
+		var _, err = c.readKV(context.Background(), store, prefix, source, priority)
+		if err != nil && c.loadCache(source, priority) {
+			return nil
+		}
+		return err
+	}
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	var s = &stream{ctx: ctx, cancel: cancel}
+
+	c.lock.Lock()
+	if old, ok := c.timerWatchers[source]; ok {
+		old.Stop()
+	}
+	c.timerWatchers[source] = s
+	c.lock.Unlock()
+
+	// A failed read is retried by the watching.
+	var revision, err = c.readKV(ctx, store, prefix, source, priority)
+	if err != nil {
+		c.loadCache(source, priority)
+	}
+
+	go c.watchKV(store, prefix, source, priority, revision, err == nil, s)
+	return nil
+}
+
+// watchKV applies changes of the store until the stream is stopped. The pairs
+// are listed again if they are not synced with the revision.
+func (c *Config) watchKV(
+	store KVStore, prefix, source string, priority int, revision uint64,
+	synced bool, s *stream,
+) {
+	var failed = !synced
+	for {
+		if failed && !s.wait(c.nextFetch(source, maxRetryDelay)) {
+			return
+		}
+		failed = false
+
+		if !synced {
+			var next, err = c.readKV(s.ctx, store, prefix, source, priority)
+			if s.ctx.Err() != nil {
+				return
+			}
+
+			if err != nil {
+				c.loadCache(source, priority)
+				failed = true
+				continue
+			}
+
+			revision, synced = next, true
+		}
+
+		var next, err = store.Wait(s.ctx, prefix, revision)
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			c.reportStatus(source, ConfigError.New(err))
+			failed = true
+		}
+
+		synced = err == nil && next == revision
+	}
+}
+
+// readKV lists the pairs of the store and applies them. It returns the
+// revision of the store.
+func (c *Config) readKV(
+	ctx context.Context, store KVStore, prefix, source string, priority int,
+) (uint64, error) {
+	var pairs, revision, err = store.List(ctx, prefix)
+	if err != nil {
+		err = ConfigError.New(err)
+		if ctx.Err() == nil {
+			c.reportStatus(source, err)
+		}
+		return 0, err
+	}
+
+	var raw = make(map[string]any)
+	for _, pair := range pairs {
+		if key := kvKey(prefix, pair.Key); key != "" {
+			raw[key] = string(pair.Value)
+		}
+	}
+
+	decrypted, err := c.newDecrypter().decrypt(raw)
+	if err != nil {
+		c.reportStatus(source, err)
+		return 0, err
+	}
+
+	var values = decrypted.(map[string]any)
+	for key, value := range values {
+		c.set(key, Value{value: value, priority: priority, strict: false, source: source})
+	}
+
+	for _, key := range c.keysOf(source) {
+		if _, ok := values[key]; !ok {
+			c.unset(key, source)
+		}
+	}
+
+	if data, err := marshalENV(raw); err == nil {
+		c.saveCache(source, ENV, data)
+	}
+
+	c.reportStatus(source, nil)
+	return revision, nil
+}
+
+// kvKey converts a key of a store to a dot-separated key relative to the
+// prefix. It returns an empty string for the prefix itself, directories, and
+// keys which are not under the prefix, such as /app2/key under /app.
+func kvKey(prefix, key string) string {
+	if !strings.HasPrefix(key, prefix) || strings.HasSuffix(key, "/") {
+		return ""
+	}
+
+	var rest = key[len(prefix):]
+	if !strings.HasSuffix(prefix, "/") && !strings.HasPrefix(rest, "/") {
+		return ""
+	}
+
+	rest = strings.Trim(rest, "/")
+	return strings.ReplaceAll(rest, "/", ".")
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+	"github.com/xybor-x/xyconfig/kvstore"
+)
+
+// unreachableKV is a KVStore which always fails.
+type unreachableKV struct{}
+
+func (unreachableKV) List(ctx context.Context, prefix string) ([]xyconfig.KVPair, uint64, error) {
+	return nil, 0, errors.New("unreachable")
+}
+
+func (unreachableKV) Wait(ctx context.Context, prefix string, revision uint64) (uint64, error) {
+	return 0, errors.New("unreachable")
+}
+
+func TestConfigReadKV(t *testing.T) {
+	var store = kvstore.NewMemory()
+	store.Put("/app/general/timeout", "3")
+	store.Put("/app/general/", "")
+	store.Put("/app/debug", "true")
+	store.Put("/other/debug", "false")
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.ReadKV(store, "/app/", 10, false)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("general.timeout").MustInt(), 3).Test(t)
+	xycond.ExpectTrue(cfg.MustGet("debug").MustBool()).Test(t)
+
+	// Values are read non-strictly.
+	cfg.Set("debug", false, 10, true)
+	xycond.ExpectFalse(cfg.MustGet("debug").MustBool()).Test(t)
+
+	var status, ok = cfg.Status("kv:/app/")
+	xycond.ExpectTrue(ok).Test(t)
+	xycond.ExpectEqual(status.ConsecutiveFailures, 0).Test(t)
+}
+
+func TestConfigReadKVSiblingPrefix(t *testing.T) {
+	var store = kvstore.NewMemory()
+	store.Put("/services/app/debug", "true")
+	store.Put("/services/application/secret", "hunter2")
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadKV(store, "/services/app", 10, true)).Test(t)
+	xycond.ExpectTrue(cfg.MustGet("debug").MustBool()).Test(t)
+	xycond.ExpectEqual(len(cfg.ToMap()), 1).Test(t)
+
+	// Changes of the sibling prefix are not applied when watching.
+	store.Put("/services/application/debug", "false")
+	store.Put("/services/app/name", "app")
+	xycond.ExpectTrue(eventually(func() bool {
+		var _, ok = cfg.Get("name")
+		return ok
+	})).Test(t)
+	xycond.ExpectTrue(cfg.MustGet("debug").MustBool()).Test(t)
+	xycond.ExpectEqual(len(cfg.ToMap()), 2).Test(t)
+}
+
+func TestConfigReadKVUnWatch(t *testing.T) {
+	var store = kvstore.NewMemory()
+	store.Put("/app/name", "app")
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.ReadKV(store, "/app/", 0, true)).Test(t)
+	xycond.ExpectNil(cfg.UnWatch("kv:/app/")).Test(t)
+
+	store.Put("/app/name", "other")
+	time.Sleep(50 * time.Millisecond)
+	xycond.ExpectEqual(cfg.MustGet("name").MustString(), "app").Test(t)
+}
+
+func TestConfigReadKVError(t *testing.T) {
+	var cfg = xyconfig.GetConfig(t.Name())
+	var err = cfg.ReadKV(unreachableKV{}, "/app/", 0, false)
+	xycond.ExpectError(err, xyconfig.ConfigError).Test(t)
+
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadKV(unreachableKV{}, "/app/", 0, true)).Test(t)
+	var status, _ = cfg.Status("kv:/app/")
+	xycond.ExpectEqual(status.ConsecutiveFailures, 2).Test(t)
+}
+
+func TestConfigReadKVWithCache(t *testing.T) {
+	var dir = t.TempDir()
+	var store = kvstore.NewMemory()
+	store.Put("/app/db/host", "localhost")
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetCacheDir(dir)
+	xycond.ExpectNil(cfg.ReadKV(store, "/app/", 0, false)).Test(t)
+
+	var other = xyconfig.GetConfig(t.Name() + "-other")
+	other.SetCacheDir(dir)
+	xycond.ExpectNil(other.ReadKV(unreachableKV{}, "/app/", 0, false)).Test(t)
+	xycond.ExpectEqual(other.MustGet("db.host").MustString(), "localhost").Test(t)
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package kvstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xybor-x/xyconfig"
+)
+
+// defaultConsulWait is the default time limit of a blocking query.
+const defaultConsulWait = 5 * time.Minute
+
+// Consul reads the KV store of a Consul agent through its HTTP API. Keys of
+// Consul do not start with a slash, for example "services/app/".
+type Consul struct {
+	// Address is the url of the agent, for example http://127.0.0.1:8500.
+	Address string
+
+	// Token is the ACL token sent in the X-Consul-Token header if it is not
+	// empty.
+	Token string
+
+	// WaitTime is the time limit of a blocking query. The default value is 5
+	// minutes.
+	WaitTime time.Duration
+
+	// Client is the HTTP client, http.DefaultClient is used if it is nil.
+	Client *http.Client
+}
+
+// NewConsul creates an adapter of the Consul agent at the address.
+func NewConsul(address string) *Consul {
+	return &Consul{Address: address}
+}
+
+// List returns all pairs whose keys have the prefix. The revision is the
+// X-Consul-Index of the response.
+func (c *Consul) List(ctx context.Context, prefix string) ([]xyconfig.KVPair, uint64, error) {
+	var resp, index, err = c.get(ctx, prefix, url.Values{"recurse": []string{"true"}})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, index, nil
+	}
+
+	var entries []struct {
+		Key   string `json:"Key"`
+		Value []byte `json:"Value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, 0, err
+	}
+
+	var pairs = make([]xyconfig.KVPair, 0, len(entries))
+	for _, entry := range entries {
+		pairs = append(pairs, xyconfig.KVPair{Key: entry.Key, Value: entry.Value})
+	}
+
+	return pairs, index, nil
+}
+
+// Wait sends a blocking query listing the keys, which returns when the
+// X-Consul-Index changes or the wait time is over.
+func (c *Consul) Wait(ctx context.Context, prefix string, revision uint64) (uint64, error) {
+	var wait = c.WaitTime
+	if wait == 0 {
+		wait = defaultConsulWait
+	}
+
+	var resp, index, err = c.get(ctx, prefix, url.Values{
+		"keys":  []string{"true"},
+		"index": []string{strconv.FormatUint(revision, 10)},
+		"wait":  []string{fmt.Sprintf("%dms", wait.Milliseconds())},
+	})
+	if err != nil {
+		return revision, err
+	}
+	resp.Body.Close()
+
+	return index, nil
+}
+
+// get sends a GET request of the prefix and returns the X-Consul-Index of the
+// response.
+func (c *Consul) get(
+	ctx context.Context, prefix string, query url.Values,
+) (*http.Response, uint64, error) {
+	var u = strings.TrimSuffix(c.Address, "/") + "/v1/kv/" +
+		strings.TrimPrefix(prefix, "/") + "?" + query.Encode()
+
+	var req, err = http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if c.Token != "" {
+		req.Header.Set("X-Consul-Token", c.Token)
+	}
+
+	resp, err := client(c.Client).Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("consul: unexpected status %s", resp.Status)
+	}
+
+	index, err := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if err != nil {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("consul: invalid X-Consul-Index (%v)", err)
+	}
+
+	return resp, index, nil
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package kvstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/xybor-x/xyconfig"
+)
+
+// Etcd reads an etcd v3 cluster through its JSON gateway (/v3/kv/range and
+// /v3/watch).
+type Etcd struct {
+	// Endpoint is the url of an etcd member, for example
+	// http://127.0.0.1:2379.
+	Endpoint string
+
+	// Token is the auth token sent in the Authorization header if it is not
+	// empty.
+	Token string
+
+	// Client is the HTTP client, http.DefaultClient is used if it is nil.
+	Client *http.Client
+}
+
+// NewEtcd creates an adapter of the etcd member at the endpoint.
+func NewEtcd(endpoint string) *Etcd {
+	return &Etcd{Endpoint: endpoint}
+}
+
+// etcdHeader is the response header of etcd.
+type etcdHeader struct {
+	Revision uint64 `json:"revision,string"`
+}
+
+// etcdKeyValue is a key-value pair of etcd, keys and values are base64
+// encoded by the JSON gateway.
+type etcdKeyValue struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// List returns all pairs whose keys have the prefix.
+func (e *Etcd) List(ctx context.Context, prefix string) ([]xyconfig.KVPair, uint64, error) {
+	var resp, err = e.post(ctx, "/v3/kv/range", map[string]any{
+		"key":       []byte(prefix),
+		"range_end": prefixEnd(prefix),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Header etcdHeader     `json:"header"`
+		Kvs    []etcdKeyValue `json:"kvs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, 0, err
+	}
+
+	var pairs = make([]xyconfig.KVPair, 0, len(result.Kvs))
+	for _, kv := range result.Kvs {
+		pairs = append(pairs, xyconfig.KVPair{Key: string(kv.Key), Value: kv.Value})
+	}
+
+	return pairs, result.Header.Revision, nil
+}
+
+// Wait watches the prefix from the next revision and returns the revision of
+// the first change.
+func (e *Etcd) Wait(ctx context.Context, prefix string, revision uint64) (uint64, error) {
+	var resp, err = e.post(ctx, "/v3/watch", map[string]any{
+		"create_request": map[string]any{
+			"key":            []byte(prefix),
+			"range_end":      prefixEnd(prefix),
+			"start_revision": fmt.Sprint(revision + 1),
+		},
+	})
+	if err != nil {
+		return revision, err
+	}
+	defer resp.Body.Close()
+
+	var decoder = json.NewDecoder(resp.Body)
+	for {
+		var message struct {
+			Result struct {
+				Header       etcdHeader        `json:"header"`
+				Canceled     bool              `json:"canceled"`
+				CancelReason string            `json:"cancel_reason"`
+				Events       []json.RawMessage `json:"events"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+
+		if err := decoder.Decode(&message); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return revision, err
+		}
+
+		if message.Error != nil {
+			return revision, fmt.Errorf("etcd: %s", message.Error.Message)
+		}
+
+		if message.Result.Canceled {
+			return revision, fmt.Errorf("etcd: watch canceled (%s)", message.Result.CancelReason)
+		}
+
+		if len(message.Result.Events) > 0 {
+			return message.Result.Header.Revision, nil
+		}
+	}
+}
+
+// post sends a JSON request to etcd.
+func (e *Etcd) post(ctx context.Context, path string, body any) (*http.Response, error) {
+	var data, err = json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var url = strings.TrimSuffix(e.Endpoint, "/") + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if e.Token != "" {
+		req.Header.Set("Authorization", e.Token)
+	}
+
+	resp, err := client(e.Client).Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("etcd: unexpected status %s", resp.Status)
+	}
+
+	return resp, nil
+}
+
+// prefixEnd returns the end of the range of keys with the prefix, as the
+// etcd clientv3.GetPrefixRangeEnd does.
+func prefixEnd(prefix string) []byte {
+	var end = []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+
+	// All keys are greater than the prefix.
+	return []byte{0}
+}
+
+// client returns the client, or http.DefaultClient if it is nil.
+func client(c *http.Client) *http.Client {
+	if c == nil {
+		return http.DefaultClient
+	}
+	return c
+}
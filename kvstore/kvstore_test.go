@@ -0,0 +1,219 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package kvstore_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+	"github.com/xybor-x/xyconfig/kvstore"
+)
+
+func eventually(cond func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+// newEtcdServer creates a fake etcd JSON gateway backed by the store.
+func newEtcdServer(store *kvstore.Memory) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			var req struct {
+				Key []byte `json:"key"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+
+			var pairs, revision, _ = store.List(r.Context(), string(req.Key))
+			var kvs []map[string]any
+			for _, p := range pairs {
+				kvs = append(kvs, map[string]any{"key": []byte(p.Key), "value": p.Value})
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"header": map[string]any{"revision": fmt.Sprint(revision)},
+				"kvs":    kvs,
+			})
+
+		case "/v3/watch":
+			var req struct {
+				CreateRequest struct {
+					Key           []byte `json:"key"`
+					StartRevision string `json:"start_revision"`
+				} `json:"create_request"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			var start, _ = strconv.ParseUint(req.CreateRequest.StartRevision, 10, 64)
+
+			fmt.Fprint(w, `{"result":{"header":{"revision":"0"},"created":true}}`+"\n")
+			w.(http.Flusher).Flush()
+
+			var revision, err = store.Wait(r.Context(), string(req.CreateRequest.Key), start-1)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, `{"result":{"header":{"revision":"%d"},"events":[{}]}}`+"\n", revision)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+// newConsulServer creates a fake Consul agent backed by the store.
+func newConsulServer(store *kvstore.Memory) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		var prefix = strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		var ctx = r.Context()
+		if index := r.URL.Query().Get("index"); index != "" {
+			var revision, _ = strconv.ParseUint(index, 10, 64)
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Second)
+			defer cancel()
+			store.Wait(ctx, prefix, revision)
+		}
+
+		var pairs, revision, _ = store.List(r.Context(), prefix)
+		w.Header().Set("X-Consul-Index", fmt.Sprint(revision))
+		if len(pairs) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var entries []map[string]any
+		for _, p := range pairs {
+			entries = append(entries, map[string]any{"Key": p.Key, "Value": p.Value})
+		}
+		json.NewEncoder(w).Encode(entries)
+	}))
+}
+
+func testStore(t *testing.T, store xyconfig.KVStore, backend *kvstore.Memory, prefix string) {
+	backend.Put(prefix+"general/timeout", "3s")
+	backend.Put(prefix+"name", "app")
+	backend.Put("other/name", "other")
+
+	var pairs, revision, err = store.List(context.Background(), prefix)
+	xycond.ExpectNil(err).Test(t)
+	xycond.ExpectEqual(len(pairs), 2).Test(t)
+	xycond.ExpectEqual(revision, uint64(3)).Test(t)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadKV(store, prefix, 10, true)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("general.timeout").MustDuration(), 3*time.Second).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("name").MustString(), "app").Test(t)
+
+	backend.Put(prefix+"general/timeout", "5s")
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("general.timeout").MustDuration() == 5*time.Second
+	})).Test(t)
+
+	backend.Delete(prefix + "name")
+	xycond.ExpectTrue(eventually(func() bool {
+		var _, ok = cfg.Get("name")
+		return !ok
+	})).Test(t)
+}
+
+func TestMemory(t *testing.T) {
+	var store = kvstore.NewMemory()
+	testStore(t, store, store, "/services/app/")
+
+	var _, revision, _ = store.List(context.Background(), "/")
+	var ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	var _, err = store.Wait(ctx, "/", revision)
+	xycond.ExpectNotNil(err).Test(t)
+}
+
+func TestEtcd(t *testing.T) {
+	var backend = kvstore.NewMemory()
+	var server = newEtcdServer(backend)
+	defer server.Close()
+
+	testStore(t, kvstore.NewEtcd(server.URL), backend, "/services/app/")
+}
+
+func TestEtcdError(t *testing.T) {
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v3/watch" {
+			fmt.Fprint(w, `{"result":{"canceled":true,"cancel_reason":"compacted"}}`)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	var store = kvstore.NewEtcd(server.URL)
+	var _, _, err = store.List(context.Background(), "/")
+	xycond.ExpectNotNil(err).Test(t)
+
+	_, err = store.Wait(context.Background(), "/", 1)
+	xycond.ExpectNotNil(err).Test(t)
+}
+
+func TestConsul(t *testing.T) {
+	var backend = kvstore.NewMemory()
+	var server = newConsulServer(backend)
+	defer server.Close()
+
+	var store = kvstore.NewConsul(server.URL)
+	store.Token = "token"
+	store.WaitTime = time.Second
+
+	var pairs, _, err = store.List(context.Background(), "services/app/")
+	xycond.ExpectNil(err).Test(t)
+	xycond.ExpectEqual(len(pairs), 0).Test(t)
+
+	testStore(t, store, backend, "services/app/")
+}
+
+func TestConsulError(t *testing.T) {
+	var backend = kvstore.NewMemory()
+	var server = newConsulServer(backend)
+	defer server.Close()
+
+	var store = kvstore.NewConsul(server.URL)
+	var _, _, err = store.List(context.Background(), "services/app/")
+	xycond.ExpectNotNil(err).Test(t)
+
+	var _, waitErr = store.Wait(context.Background(), "services/app/", 1)
+	xycond.ExpectNotNil(waitErr).Test(t)
+}
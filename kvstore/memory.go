@@ -0,0 +1,105 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package kvstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xybor-x/xyconfig"
+)
+
+// Memory is an in-memory key-value store. Every change increases its
+// revision.
+type Memory struct {
+	lock     sync.Mutex
+	pairs    map[string][]byte
+	revision uint64
+	changed  chan struct{}
+}
+
+// NewMemory creates an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{pairs: make(map[string][]byte), changed: make(chan struct{})}
+}
+
+// Put sets the value of the key.
+func (m *Memory) Put(key string, value string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.pairs[key] = []byte(value)
+	m.notify()
+}
+
+// Delete removes the key.
+func (m *Memory) Delete(key string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.pairs[key]; ok {
+		delete(m.pairs, key)
+		m.notify()
+	}
+}
+
+// List returns all pairs whose keys have the prefix, sorted by keys.
+func (m *Memory) List(ctx context.Context, prefix string) ([]xyconfig.KVPair, uint64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var pairs []xyconfig.KVPair
+	for key, value := range m.pairs {
+		if strings.HasPrefix(key, prefix) {
+			pairs = append(pairs, xyconfig.KVPair{Key: key, Value: value})
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs, m.revision, nil
+}
+
+// Wait blocks until the store changes after the revision. It does not check
+// whether the changed key has the prefix.
+func (m *Memory) Wait(ctx context.Context, prefix string, revision uint64) (uint64, error) {
+	for {
+		m.lock.Lock()
+		var current, changed = m.revision, m.changed
+		m.lock.Unlock()
+
+		if current != revision {
+			return current, nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return revision, ctx.Err()
+		}
+	}
+}
+
+// notify increases the revision and wakes up waiters. The lock must be held.
+func (m *Memory) notify() {
+	m.revision++
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"github.com/joho/godotenv"
+)
+
+// Marshal serializes the Config under the format. The output can be read again
+// by ReadBytes.
+//
+// Formats which do not support nested values (INI, ENV) use dot-separated keys
+// or sections for sub-Configs, and arrays are written as comma-separated lists.
+// INI keys outside any section are read as DEFAULT.key, so the DEFAULT
+// sub-Config is written there, and other top-level values can not be written
+// under INI.
+//
+// Values which were read encrypted are written with their original ciphertext,
+// so the output does not contain their plaintext.
+func (c *Config) Marshal(format Format) ([]byte, error) {
+	return marshalMap(format, c.toMap(false))
+}
+
+// WriteFile writes the Config to a file atomically. The format is chosen by the
+// extension of filename.
+func (c *Config) WriteFile(filename string) error {
+	var format = getFormat(filename)
+	if format == UnknownFormat {
+		return FormatError.Newf("unknown extension: %s", filename)
+	}
+
+	var data, err = c.Marshal(format)
+	if err != nil {
+		return err
+	}
+
+	return c.writeFile(filename, data)
+}
+
+// MarshalMap serializes a map under the format as Config.Marshal does, for
+// example a map returned by ToMap.
+func MarshalMap(format Format, m map[string]any) ([]byte, error) {
+	return marshalMap(format, m)
+}
+
+// marshalMap serializes a map under the format.
+func marshalMap(format Format, m map[string]any) ([]byte, error) {
+	switch format {
+	case JSON:
+		return marshalJSON(m)
+	case INI:
+		return marshalINI(m)
+	case ENV:
+		return marshalENV(m)
+	default:
+		return nil, FormatError.New("unsupported format")
+	}
+}
+
+func marshalJSON(m map[string]any) ([]byte, error) {
+	var data, err = json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, FormatError.Newf("cannot marshal json data (%v)", err)
+	}
+	return data, nil
+}
+
+func marshalINI(m map[string]any) ([]byte, error) {
+	// Only the DEFAULT sub-Config can be written outside of sections.
+	for _, k := range sortedKeys(m) {
+		if _, ok := m[k].(map[string]any); !ok {
+			return nil, FormatError.Newf(
+				"cannot marshal top-level key %s to ini, move it to %s.%s", k, ini.DefaultSection, k)
+		}
+	}
+
+	var file = ini.Empty()
+	if err := writeINISection(file, "", m); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if _, err := file.WriteTo(&buf); err != nil {
+		return nil, FormatError.Newf("cannot marshal ini data (%v)", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeINISection writes the non-map values of m to the section, and each
+// nested map to a sub-section whose name is dot-separated.
+func writeINISection(file *ini.File, name string, m map[string]any) error {
+	var section = file.Section(name)
+	var keys = sortedKeys(m)
+
+	for _, k := range keys {
+		if _, ok := m[k].(map[string]any); ok {
+			continue
+		}
+		if _, err := section.NewKey(k, formatValue(m[k])); err != nil {
+			return FormatError.Newf("cannot marshal ini key %s (%v)", k, err)
+		}
+	}
+
+	for _, k := range keys {
+		if sub, ok := m[k].(map[string]any); ok {
+			var subname = k
+			if name != "" {
+				subname = name + "." + k
+			}
+			if err := writeINISection(file, subname, sub); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func marshalENV(m map[string]any) ([]byte, error) {
+	var envmap = make(map[string]string)
+	flattenMap("", m, envmap)
+
+	var s, err = godotenv.Marshal(envmap)
+	if err != nil {
+		return nil, FormatError.Newf("cannot marshal env data (%v)", err)
+	}
+	return []byte(s + "\n"), nil
+}
+
+// flattenMap puts all non-map values of m to result with dot-separated keys.
+func flattenMap(prefix string, m map[string]any, result map[string]string) {
+	for k, v := range m {
+		if sub, ok := v.(map[string]any); ok {
+			flattenMap(prefix+k+".", sub, result)
+		} else {
+			result[prefix+k] = formatValue(v)
+		}
+	}
+}
+
+// formatValue returns the string representation of a value for formats which
+// only support string values.
+func formatValue(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case []any:
+		var elements = make([]string, len(t))
+		for i := range t {
+			elements[i] = formatValue(t[i])
+		}
+		return strings.Join(elements, ",")
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// sortedKeys returns the keys of map in the increasing order.
+func sortedKeys(m map[string]any) []string {
+	var keys = make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+func newMarshalConfig(t *testing.T) *xyconfig.Config {
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.ReadJSON(0, []byte(`{
+		"name": "app",
+		"general": {"timeout": 3.14, "retry": 3, "debug": true},
+		"db": {"primary": {"host": "localhost"}},
+		"hosts": ["a", "b"]
+	}`))
+	return cfg
+}
+
+func expectMarshalConfig(t *testing.T, cfg *xyconfig.Config) {
+	xycond.ExpectEqual(cfg.MustGet("name").MustString(), "app").Test(t)
+	xycond.ExpectEqual(cfg.MustGet("general.timeout").MustFloat(), 3.14).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("general.retry").MustInt(), 3).Test(t)
+	xycond.ExpectTrue(cfg.MustGet("general.debug").MustBool()).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("db.primary.host").MustString(), "localhost").Test(t)
+	xycond.ExpectEqual(cfg.MustGet("hosts").MustArray()[1].MustString(), "b").Test(t)
+}
+
+func TestConfigMarshalJSON(t *testing.T) {
+	var data, err = newMarshalConfig(t).Marshal(xyconfig.JSON)
+	xycond.ExpectNil(err).Test(t)
+
+	var cfg = xyconfig.GetConfig(t.Name() + "-read")
+	xycond.ExpectNil(cfg.ReadBytes(xyconfig.JSON, 0, data)).Test(t)
+	expectMarshalConfig(t, cfg)
+}
+
+func TestConfigMarshalINI(t *testing.T) {
+	var _, err = newMarshalConfig(t).Marshal(xyconfig.INI)
+	xycond.ExpectError(err, xyconfig.FormatError).Test(t)
+
+	var cfg = xyconfig.GetConfig(t.Name() + "-default")
+	cfg.ReadINI(0, []byte("name=app\n[general]\ntimeout=3.14\n[db.primary]\nhost=localhost"))
+	data, err := cfg.Marshal(xyconfig.INI)
+	xycond.ExpectNil(err).Test(t)
+
+	var read = xyconfig.GetConfig(t.Name() + "-read")
+	xycond.ExpectNil(read.ReadBytes(xyconfig.INI, 0, data)).Test(t)
+	xycond.ExpectEqual(read.MustGet("DEFAULT.name").MustString(), "app").Test(t)
+	xycond.ExpectEqual(read.MustGet("general.timeout").MustFloat(), 3.14).Test(t)
+	xycond.ExpectEqual(read.MustGet("db.primary.host").MustString(), "localhost").Test(t)
+}
+
+func TestConfigMarshalENV(t *testing.T) {
+	var data, err = newMarshalConfig(t).Marshal(xyconfig.ENV)
+	xycond.ExpectNil(err).Test(t)
+
+	var cfg = xyconfig.GetConfig(t.Name() + "-read")
+	xycond.ExpectNil(cfg.ReadBytes(xyconfig.ENV, 0, data)).Test(t)
+	expectMarshalConfig(t, cfg)
+}
+
+func TestConfigMarshalUnknown(t *testing.T) {
+	var _, err = newMarshalConfig(t).Marshal(xyconfig.UnknownFormat)
+	xycond.ExpectError(err, xyconfig.FormatError).Test(t)
+}
+
+func TestConfigMarshalSensitive(t *testing.T) {
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.AddSensitive("password")
+	cfg.Set("password", "secret", 0, true)
+
+	var data, err = cfg.Marshal(xyconfig.JSON)
+	xycond.ExpectNil(err).Test(t)
+	xycond.ExpectIn("secret", string(data)).Test(t)
+}
+
+func TestConfigMarshalEncrypted(t *testing.T) {
+	var key = newTestKey(t)
+	var encrypted, _ = xyconfig.Encrypt(key, "hunter2")
+	os.Setenv(t.Name(), base64.StdEncoding.EncodeToString(key))
+	defer os.Unsetenv(t.Name())
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetKeyProvider(xyconfig.KeyEnv(t.Name()))
+	xycond.ExpectNil(cfg.ReadJSON(0, []byte(`{"db": {"password": "`+encrypted+`"}}`))).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("db.password").MustString(), "hunter2").Test(t)
+
+	for _, format := range []xyconfig.Format{xyconfig.JSON, xyconfig.INI, xyconfig.ENV} {
+		var data, err = cfg.Marshal(format)
+		xycond.ExpectNil(err).Test(t)
+		xycond.ExpectFalse(strings.Contains(string(data), "hunter2")).Test(t)
+		xycond.ExpectIn(encrypted, string(data)).Test(t)
+
+		var read = xyconfig.GetConfig(t.Name() + "-read")
+		read.SetKeyProvider(xyconfig.KeyEnv(t.Name()))
+		xycond.ExpectNil(read.ReadBytes(format, 0, data)).Test(t)
+		xycond.ExpectEqual(read.MustGet("db.password").MustString(), "hunter2").Test(t)
+	}
+}
+
+func TestConfigWriteFile(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "config.env")
+	xycond.ExpectNil(newMarshalConfig(t).WriteFile(filename)).Test(t)
+
+	var cfg = xyconfig.GetConfig(t.Name() + "-read")
+	xycond.ExpectNil(cfg.ReadFile(filename, false)).Test(t)
+	expectMarshalConfig(t, cfg)
+}
+
+func TestConfigWriteFileUnknownExt(t *testing.T) {
+	var err = newMarshalConfig(t).WriteFile(filepath.Join(t.TempDir(), "config.unk"))
+	xycond.ExpectError(err, xyconfig.FormatError).Test(t)
+}
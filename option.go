@@ -0,0 +1,142 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import "time"
+
+// ReadOption configures how Read, ReadFile, ReadDir, ReadFS, ReadProfile, ReadS3
+// and ReadHTTP read an instance. Options which are not given fall back to the
+// defaults: the format is chosen by the extension, the priority is extracted
+// from the file name, and the instance is watched as the arguments of the
+// method say (Read uses the interval set by SetWatchInterval). Options which do
+// not apply to an instance are ignored.
+//
+// For example:
+//    config.ReadFile("/etc/team/app.json", false, xyconfig.WithPriority(30), xyconfig.WithWatch())
+//    config.ReadDir("/etc/team", "*.json", true, xyconfig.WithPriority(30))
+type ReadOption func(*readOptions)
+
+// readOptions contains the read options of an instance.
+type readOptions struct {
+	format   Format
+	priority *int
+	watch    *bool
+	interval time.Duration
+}
+
+// WithFormat sets the format of the instance, instead of choosing it by the
+// extension or detecting it from the content. ReadDir and ReadFS also read the
+// files with unknown extensions which match the pattern. It is ignored when
+// reading environment variables or profiles.
+func WithFormat(format Format) ReadOption {
+	return func(o *readOptions) {
+		o.format = format
+	}
+}
+
+// WithPriority sets the priority of the instance, instead of extracting it from
+// the file name. The priority is kept when the instance is reloaded.
+func WithPriority(priority int) ReadOption {
+	return func(o *readOptions) {
+		o.priority = &priority
+	}
+}
+
+// WithWatch watches the changes of the instance even if the interval set by
+// SetWatchInterval is zero. Files are watched by inotify, other instances are
+// read again every interval (5 minutes by default).
+func WithWatch() ReadOption {
+	return func(o *readOptions) {
+		var watch = true
+		o.watch = &watch
+	}
+}
+
+// WithInterval sets the time interval to watch the changes of the instance.
+// Files are polled with the interval instead of being watched by inotify,
+// except the files of ReadDir, which must be watched by inotify to find new
+// files. Set the interval as zero to not watch the instance.
+func WithInterval(d time.Duration) ReadOption {
+	return func(o *readOptions) {
+		var watch = d > 0
+		o.watch = &watch
+		o.interval = d
+	}
+}
+
+// newReadOptions applies the options.
+func newReadOptions(opts []ReadOption) readOptions {
+	var o readOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// getPriority returns the priority given by WithPriority, or def if no priority
+// was given.
+func (o readOptions) getPriority(def int) int {
+	if o.priority != nil {
+		return *o.priority
+	}
+	return def
+}
+
+// getWatch returns true if the instance is watched. The def is given by the
+// arguments of the method.
+func (o readOptions) getWatch(def bool) bool {
+	if o.watch != nil {
+		return *o.watch
+	}
+	return def
+}
+
+// getInterval returns the interval to watch the instance, or zero if it is not
+// watched. The def is the interval set by SetWatchInterval.
+func (o readOptions) getInterval(def time.Duration) time.Duration {
+	var watch = def > 0
+	if o.watch != nil {
+		watch = *o.watch
+	}
+
+	switch {
+	case !watch:
+		return 0
+	case o.interval > 0:
+		return o.interval
+	case def > 0:
+		return def
+	default:
+		return defaultWatchInterval
+	}
+}
+
+// setSourceFormat sets the format of the source. The source is reset to the
+// default detection if the format is UnknownFormat.
+func (c *Config) setSourceFormat(source string, format Format) {
+	c.lock.WLockFunc(func() {
+		if format == UnknownFormat {
+			delete(c.formats, source)
+		} else {
+			c.formats[source] = format
+		}
+	})
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+func TestConfigReadFileDetectFormat(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config":  "[server]\nhost = localhost",
+		"latest":  `{"port": 8080}`,
+		"app.txt": "DEBUG=true",
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.ReadFile(filepath.Join(dir, "config"), false)).Test(t)
+	xycond.ExpectNil(cfg.Read(filepath.Join(dir, "latest"))).Test(t)
+	xycond.ExpectNil(cfg.Read(filepath.Join(dir, "app.txt"))).Test(t)
+	cfg.CloseWatcher()
+
+	xycond.ExpectEqual(cfg.MustGet("server.host").MustString(), "localhost").Test(t)
+	xycond.ExpectEqual(cfg.MustGet("port").MustInt(), 8080).Test(t)
+	xycond.ExpectTrue(cfg.MustGet("DEBUG").MustBool()).Test(t)
+}
+
+func TestConfigReadWithFormat(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.conf": "host = localhost\n[db]\nport = 5432",
+		"app.json": "host = example.com",
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetWatchInterval(0)
+
+	var err = cfg.Read(filepath.Join(dir, "app.conf"), xyconfig.WithFormat(xyconfig.INI))
+	xycond.ExpectNil(err).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("db.port").MustInt(), 5432).Test(t)
+
+	// The format overrides the extension.
+	xycond.ExpectNotNil(cfg.Read(filepath.Join(dir, "app.json"))).Test(t)
+	err = cfg.Read(filepath.Join(dir, "app.json"), xyconfig.WithFormat(xyconfig.ENV))
+	xycond.ExpectNil(err).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("host").MustString(), "example.com").Test(t)
+}
+
+func TestConfigReadS3DetectFormat(t *testing.T) {
+	var client = newFakeS3()
+	client.put("bucket", "latest", `{"foo": "bar"}`, `"1"`)
+	client.put("bucket", "app.txt", "[general]\nfoo = buzz", `"1"`)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetS3Client(client)
+	cfg.SetWatchInterval(0)
+
+	xycond.ExpectNil(cfg.Read("s3://bucket/latest")).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+
+	var err = cfg.Read("s3://bucket/app.txt", xyconfig.WithFormat(xyconfig.INI))
+	xycond.ExpectNil(err).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("general.foo").MustString(), "buzz").Test(t)
+}
+
+func TestConfigReadWithPriority(t *testing.T) {
+	var dir = t.TempDir()
+	var filename = filepath.Join(dir, "10-app.json")
+	writeFiles(t, dir, map[string]string{"10-app.json": `{"foo": "bar"}`})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+
+	xycond.ExpectNil(cfg.Read(filename, xyconfig.WithPriority(30), xyconfig.WithWatch())).Test(t)
+	cfg.Set("foo", "low", 20, true)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+
+	// The priority is kept when the file is reloaded.
+	ioutil.WriteFile(filename, []byte(`{"foo": "buzz"}`), 0644)
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustString() == "buzz"
+	})).Test(t)
+	cfg.Set("foo", "low", 20, true)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "buzz").Test(t)
+}
+
+func TestConfigReadFileWithOptions(t *testing.T) {
+	var dir = t.TempDir()
+	var filename = filepath.Join(dir, "app.json")
+	writeFiles(t, dir, map[string]string{"app.json": `{"foo": "bar"}`})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+
+	var err = cfg.ReadFile(filename, false, xyconfig.WithPriority(30), xyconfig.WithWatch())
+	xycond.ExpectNil(err).Test(t)
+	cfg.Set("foo", "low", 20, true)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+
+	ioutil.WriteFile(filename, []byte(`{"foo": "buzz"}`), 0644)
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustString() == "buzz"
+	})).Test(t)
+}
+
+func TestConfigReadDirWithOptions(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.conf":  "[db]\nhost = localhost",
+		"README.md": "# readme",
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+
+	var err = cfg.ReadDir(dir, "*.conf", true, xyconfig.WithPriority(30), xyconfig.WithFormat(xyconfig.INI))
+	xycond.ExpectNil(err).Test(t)
+	cfg.Set("db.host", "low", 20, true)
+	xycond.ExpectEqual(cfg.MustGet("db.host").MustString(), "localhost").Test(t)
+
+	// Files added later are read with the options.
+	writeFiles(t, dir, map[string]string{"extra.conf": "[db]\nport = 5432"})
+	xycond.ExpectTrue(eventually(func() bool {
+		var _, ok = cfg.Get("db.port")
+		return ok
+	})).Test(t)
+	cfg.Set("db.port", 1, 20, true)
+	xycond.ExpectEqual(cfg.MustGet("db.port").MustInt(), 5432).Test(t)
+}
+
+func TestConfigReadFSWithOptions(t *testing.T) {
+	var fsys = fstest.MapFS{
+		"app.conf": {Data: []byte("[db]\nhost = localhost")},
+	}
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	var err = cfg.ReadFS(fsys, "*.conf", xyconfig.WithPriority(30), xyconfig.WithFormat(xyconfig.INI))
+	xycond.ExpectNil(err).Test(t)
+
+	cfg.Set("db.host", "low", 20, true)
+	xycond.ExpectEqual(cfg.MustGet("db.host").MustString(), "localhost").Test(t)
+}
+
+func TestConfigReadProfileWithPriority(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.json":     `{"host": "localhost", "port": 8080}`,
+		"app.dev.json": `{"host": "dev.local"}`,
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetProfile("dev")
+	var err = cfg.ReadProfile(filepath.Join(dir, "app"), false, xyconfig.WithPriority(30))
+	xycond.ExpectNil(err).Test(t)
+
+	// Base files have the priority 30 and profile files have the priority 31.
+	cfg.Set("port", 1, 20, true)
+	cfg.Set("host", "other", 30, true)
+	xycond.ExpectEqual(cfg.MustGet("port").MustInt(), 8080).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("host").MustString(), "dev.local").Test(t)
+}
+
+func TestConfigReadWithInterval(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "app.json")
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+
+	var err = cfg.Read(filename, xyconfig.WithPriority(30), xyconfig.WithInterval(10*time.Millisecond))
+	xycond.ExpectNil(err).Test(t)
+
+	// The file is read with the priority when it is created.
+	ioutil.WriteFile(filename, []byte(`{"foo": "bar"}`), 0644)
+	xycond.ExpectTrue(eventually(func() bool {
+		var v, ok = cfg.Get("foo")
+		return ok && v.MustString() == "bar"
+	})).Test(t)
+	cfg.Set("foo", "low", 20, true)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+
+	xycond.ExpectNil(cfg.Read(filename, xyconfig.WithInterval(0))).Test(t)
+	xycond.ExpectNil(cfg.UnWatch(filename)).Test(t)
+}
+
+func TestConfigReadS3WithPriority(t *testing.T) {
+	var client = newFakeS3()
+	client.put("bucket", "app.json", `{"foo": "bar"}`, `"1"`)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetS3Client(client)
+
+	var err = cfg.Read("s3://bucket/app.json", xyconfig.WithPriority(30), xyconfig.WithInterval(0))
+	xycond.ExpectNil(err).Test(t)
+	cfg.Set("foo", "low", 20, true)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+}
+
+func TestConfigLoadEnvWithPriority(t *testing.T) {
+	os.Setenv(t.Name(), "env")
+	defer os.Unsetenv(t.Name())
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.Read("env", xyconfig.WithPriority(5), xyconfig.WithInterval(0))).Test(t)
+	xycond.ExpectEqual(cfg.MustGet(t.Name()).MustString(), "env").Test(t)
+
+	cfg.Set(t.Name(), "set", 10, true)
+	xycond.ExpectEqual(cfg.MustGet(t.Name()).MustString(), "set").Test(t)
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"github.com/joho/godotenv"
+	"github.com/xybor-x/xylock"
+)
+
+// overrideFile persists changes made by Set, so they are not lost on restart.
+type overrideFile struct {
+	// filename is the path of the override file.
+	filename string
+
+	// format is the format of the override file.
+	format Format
+
+	// priority is the priority of values read from the override file.
+	priority int
+
+	// values contains all overrides with dot-separated keys.
+	values map[string]any
+
+	// lock serializes writing to the override file.
+	lock *xylock.Lock
+}
+
+// SetOverrideFile sets the file persisting changes made by Set. The file format
+// is chosen by its extension.
+//
+// Overrides persisted in the file before are read immediately with the
+// priority. Later changes made by Set are written to the file atomically (a
+// temporary file is written, then renamed to the override file). The watcher
+// ignores these writes, so they do not trigger a redundant reload.
+func (c *Config) SetOverrideFile(filename string, priority int) error {
+	var format = getFormat(filename)
+	if format == UnknownFormat {
+		return FormatError.Newf("unknown extension: %s", filename)
+	}
+
+	var override = &overrideFile{
+		filename: filename,
+		format:   format,
+		priority: priority,
+		values:   make(map[string]any),
+		lock:     &xylock.Lock{},
+	}
+
+	if data, err := ioutil.ReadFile(filename); err != nil {
+		if !os.IsNotExist(err) {
+			return ConfigError.New(err)
+		}
+	} else {
+		var values, strict, err = unmarshalFlat(format, data)
+		if err != nil {
+			return err
+		}
+
+		for k, v := range values {
+			c.set(k, Value{value: v, priority: priority, strict: strict, source: filepath.Clean(filename)})
+		}
+		override.values = values
+	}
+
+	c.lock.WLockFunc(func() {
+		c.override = override
+	})
+
+	return nil
+}
+
+// persistOverride writes the change made by Set to the override file. Nothing
+// is written if there is no override file or the value was not applied.
+func (c *Config) persistOverride(key string, value any, priority int) {
+	var override = c.lock.RLockFunc(func() any {
+		return c.override
+	}).(*overrideFile)
+
+	if override == nil {
+		return
+	}
+
+	if _, ok := value.(*Config); ok {
+		return
+	}
+
+	if v, ok := c.Get(key); !ok || v.priority > priority {
+		return
+	}
+
+	override.lock.Lock()
+	defer override.lock.Unlock()
+
+	// A key replaces its children and parents, as Set does.
+	for k := range override.values {
+		if strings.HasPrefix(k, key+".") || strings.HasPrefix(key, k+".") {
+			delete(override.values, k)
+		}
+	}
+	override.values[key] = value
+
+	var data, err = marshalMap(override.format, unflattenMap(override.values))
+	if err == nil {
+		err = c.writeFile(override.filename, data)
+	}
+
+	if err != nil {
+		logger.Event("override-error").
+			Field("filename", override.filename).Field("error", err).Warning()
+	}
+}
+
+// maxWrittenHashes is the maximum number of writes of a file remembered to not
+// reload them.
+const maxWrittenHashes = 16
+
+// writeFile writes data to filename atomically and records its content hash,
+// so the watcher will not reload this change.
+func (c *Config) writeFile(filename string, data []byte) error {
+	c.lock.WLockFunc(func() {
+		var name = filepath.Clean(filename)
+		var hash = sha256.Sum256(data)
+		c.fileHashes[name] = hash
+
+		// Only watched files will see the events of this write.
+		var _, watched = c.watchedFiles[name]
+		var _, polled = c.timerWatchers[filename]
+		if !watched && !polled {
+			return
+		}
+
+		var hashes = append(c.writtenHashes[name], hash)
+		if len(hashes) > maxWrittenHashes {
+			hashes = hashes[len(hashes)-maxWrittenHashes:]
+		}
+		c.writtenHashes[name] = hashes
+	})
+	return writeFileAtomic(filename, data)
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory, then
+// renames it to filename.
+func writeFileAtomic(filename string, data []byte) error {
+	var dir, base = filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return ConfigError.New(err)
+	}
+
+	var tmp, err = ioutil.TempFile(dir, "."+base+".tmp*")
+	if err != nil {
+		return ConfigError.New(err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return ConfigError.New(err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return ConfigError.New(err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return ConfigError.New(err)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return ConfigError.New(err)
+	}
+
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return ConfigError.New(err)
+	}
+
+	return nil
+}
+
+// unmarshalFlat parses data under the format to a map with dot-separated keys.
+// The latter returned value says if values should be strictly typed.
+func unmarshalFlat(format Format, data []byte) (map[string]any, bool, error) {
+	var result = make(map[string]any)
+
+	switch format {
+	case JSON:
+		var m map[string]any
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, false, parseError(JSON, data, err)
+		}
+		flattenAny("", m, result)
+		return result, true, nil
+
+	case INI:
+		var cfg, err = ini.Load(data)
+		if err != nil {
+			return nil, false, parseError(INI, data, err)
+		}
+
+		for _, section := range cfg.Sections() {
+			for _, key := range section.Keys() {
+				result[section.Name()+"."+key.Name()] = key.Value()
+			}
+		}
+		return result, false, nil
+
+	case ENV:
+		var envmap, err = godotenv.Unmarshal(string(data))
+		if err != nil {
+			return nil, false, ConfigError.New(err)
+		}
+
+		for k, v := range envmap {
+			result[k] = v
+		}
+		return result, false, nil
+
+	default:
+		return nil, false, FormatError.New("unsupported format")
+	}
+}
+
+// flattenAny puts all non-map values of m to result with dot-separated keys.
+func flattenAny(prefix string, m map[string]any, result map[string]any) {
+	for k, v := range m {
+		if sub, ok := v.(map[string]any); ok {
+			flattenAny(prefix+k+".", sub, result)
+		} else {
+			result[prefix+k] = v
+		}
+	}
+}
+
+// unflattenMap converts a map with dot-separated keys to a nested map.
+func unflattenMap(m map[string]any) map[string]any {
+	var result = make(map[string]any)
+	for k, v := range m {
+		var current = result
+		var parts = strings.Split(k, ".")
+		for _, p := range parts[:len(parts)-1] {
+			var sub, ok = current[p].(map[string]any)
+			if !ok {
+				sub = make(map[string]any)
+				current[p] = sub
+			}
+			current = sub
+		}
+		current[parts[len(parts)-1]] = v
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+func TestConfigSetOverrideFile(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "override.json")
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.SetOverrideFile(filename, 50)).Test(t)
+	cfg.Set("general.timeout", 10.0, 50, true)
+	cfg.Set("general.system", "linux", 50, true)
+
+	var restarted = xyconfig.GetConfig(t.Name() + "-restarted")
+	xycond.ExpectNil(restarted.SetOverrideFile(filename, 50)).Test(t)
+	xycond.ExpectEqual(restarted.MustGet("general.timeout").MustFloat(), 10.0).Test(t)
+	xycond.ExpectEqual(restarted.MustGet("general.system").MustString(), "linux").Test(t)
+	xycond.ExpectEqual(restarted.MustGet("general.timeout").MustInt(), 10).Test(t)
+}
+
+func TestConfigSetOverrideFileINI(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "override.ini")
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.SetOverrideFile(filename, 50)).Test(t)
+	cfg.Set("general.timeout", 10, 50, true)
+	cfg.Set("DEFAULT.debug", true, 50, true)
+
+	var restarted = xyconfig.GetConfig(t.Name() + "-restarted")
+	xycond.ExpectNil(restarted.SetOverrideFile(filename, 50)).Test(t)
+	xycond.ExpectEqual(restarted.MustGet("general.timeout").MustInt(), 10).Test(t)
+	xycond.ExpectTrue(restarted.MustGet("DEFAULT.debug").MustBool()).Test(t)
+}
+
+func TestConfigSetOverrideFileReplaceParent(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "override.json")
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.SetOverrideFile(filename, 50)).Test(t)
+	cfg.Set("general", "none", 50, true)
+	cfg.Set("general.timeout", 10, 50, true)
+
+	var restarted = xyconfig.GetConfig(t.Name() + "-restarted")
+	xycond.ExpectNil(restarted.SetOverrideFile(filename, 50)).Test(t)
+	xycond.ExpectEqual(restarted.MustGet("general.timeout").MustInt(), 10).Test(t)
+}
+
+func TestConfigSetOverrideFileLowerPriority(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "override.json")
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.Set("foo", "bar", 60, true)
+	xycond.ExpectNil(cfg.SetOverrideFile(filename, 50)).Test(t)
+	cfg.Set("foo", "buzz", 50, true)
+
+	var _, err = ioutil.ReadFile(filename)
+	xycond.ExpectNotNil(err).Test(t)
+}
+
+func TestConfigSetOverrideFileUnknownExt(t *testing.T) {
+	var cfg = xyconfig.GetConfig(t.Name())
+	var err = cfg.SetOverrideFile(filepath.Join(t.TempDir(), "override.unk"), 50)
+	xycond.ExpectError(err, xyconfig.FormatError).Test(t)
+}
+
+func TestConfigSetOverrideFileInvalid(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "override.json")
+	ioutil.WriteFile(filename, []byte(`{"error`), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	var err = cfg.SetOverrideFile(filename, 50)
+	xycond.ExpectNotNil(err).Test(t)
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"os"
+	"time"
+)
+
+// SetPollInterval sets the time interval to poll files when they cannot be
+// watched by inotify. The default interval is 5 seconds.
+func (c *Config) SetPollInterval(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.pollInterval = d
+}
+
+// PollFile reads the config values from a file and polls for its changes every
+// duration. Use this method instead of ReadFile on filesystems which never
+// deliver inotify events, such as NFS, some overlay and FUSE filesystems.
+//
+// The content of the file is hashed at every poll, and the file is reloaded if
+// the hash differs from the last read. The modification time and the size are
+// not used, since a rewrite of the same size within the precision of the
+// modification time (1s on many NFS mounts) does not change them. Values of the
+// file are removed if the file is removed. It is ok if the file does not exist
+// yet.
+func (c *Config) PollFile(filename string, d time.Duration) error {
+	return c.readPolledFile(filename, getPriority(filename), d)
+}
+
+// readPolledFile reads the file with the priority and polls for its changes
+// every duration.
+func (c *Config) readPolledFile(filename string, priority int, d time.Duration) error {
+	if d <= 0 {
+		return ConfigError.Newf("invalid poll interval %s", d)
+	}
+
+	var _, err = os.Stat(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return ConfigError.New(err)
+	}
+
+	c.setFilePriority(filename, priority)
+	if err == nil {
+		if err := c.readFile(filename, priority, false, nil); err != nil {
+			return err
+		}
+	}
+
+	c.pollFile(filename, d, err == nil)
+	return nil
+}
+
+// pollFile checks the file after the duration and schedules the next check.
+// The existed is true if the file existed at the previous check.
+func (c *Config) pollFile(filename string, d time.Duration, existed bool) {
+	// Hold the lock until the timer is stored, so the callback always sees it.
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		// Stop if the polling was stopped or replaced.
+		var current, _ = c.lock.RLockFunc(func() any {
+			return c.timerWatchers[filename]
+		}).(stopper)
+
+		if current != timer {
+			return
+		}
+
+		var _, err = os.Stat(filename)
+		if err != nil && !os.IsNotExist(err) {
+			logger.Event("poll-error").
+				Field("filename", filename).Field("error", err).Warning()
+		}
+
+		// An existing file is always checked, reloadFile skips it if its
+		// content hash is unchanged. A removed file is reloaded once to remove
+		// its values.
+		var exists = err == nil
+		if exists || existed {
+			c.reloadFile(filename)
+		}
+
+		c.pollFile(filename, d, exists)
+	})
+
+	c.timerWatchers[filename] = timer
+}
+
+// getPollInterval returns the current poll interval.
+func (c *Config) getPollInterval() time.Duration {
+	return c.lock.RLockFunc(func() any {
+		return c.pollInterval
+	}).(time.Duration)
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+func TestConfigPollFile(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "config.json")
+	ioutil.WriteFile(filename, []byte(`{"foo": "bar"}`), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.PollFile(filename, 10*time.Millisecond)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+
+	ioutil.WriteFile(filename, []byte(`{"foo": "buzzz"}`), 0644)
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustString() == "buzzz"
+	})).Test(t)
+
+	os.Remove(filename)
+	xycond.ExpectTrue(eventually(func() bool {
+		var _, ok = cfg.Get("foo")
+		return !ok
+	})).Test(t)
+}
+
+func TestConfigPollFileSameSizeAndTime(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "config.json")
+	ioutil.WriteFile(filename, []byte(`{"foo": "bar"}`), 0644)
+	var info, err = os.Stat(filename)
+	xycond.ExpectNil(err).Test(t)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.PollFile(filename, 10*time.Millisecond)).Test(t)
+
+	// Rewrite the file with the same size and modification time, as a coarse
+	// mtime precision does.
+	ioutil.WriteFile(filename, []byte(`{"foo": "biz"}`), 0644)
+	os.Chtimes(filename, info.ModTime(), info.ModTime())
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustString() == "biz"
+	})).Test(t)
+}
+
+func TestConfigPollFileNotExist(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "config.ini")
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.PollFile(filename, 10*time.Millisecond)).Test(t)
+
+	ioutil.WriteFile(filename, []byte("[general]\ntimeout = 3"), 0644)
+	xycond.ExpectTrue(eventually(func() bool {
+		var v, ok = cfg.Get("general.timeout")
+		return ok && v.MustInt() == 3
+	})).Test(t)
+}
+
+func TestConfigPollFileUnWatch(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "config.json")
+	ioutil.WriteFile(filename, []byte(`{"foo": "bar"}`), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.PollFile(filename, 10*time.Millisecond)).Test(t)
+	xycond.ExpectNil(cfg.UnWatch(filename)).Test(t)
+
+	ioutil.WriteFile(filename, []byte(`{"foo": "buzzz"}`), 0644)
+	time.Sleep(50 * time.Millisecond)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+}
+
+func TestConfigPollFileInvalid(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "foo.unk")
+	ioutil.WriteFile(filename, []byte("not a config"), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectError(cfg.PollFile(filename, time.Second), xyconfig.FormatError).Test(t)
+	xycond.ExpectError(cfg.PollFile("foo.json", 0), xyconfig.ConfigError).Test(t)
+}
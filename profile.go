@@ -0,0 +1,110 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// ProfileEnv is the environment variable which selects the active profile if
+// no profile is set by SetProfile.
+const ProfileEnv = "XYCONFIG_PROFILE"
+
+// SetProfile sets the active profile of the config, for example "dev" or
+// "prod". Set an empty profile to fall back to the ProfileEnv environment
+// variable.
+func (c *Config) SetProfile(profile string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.profile = profile
+}
+
+// Profile returns the active profile. It is the profile set by SetProfile, or
+// the value of the ProfileEnv environment variable.
+func (c *Config) Profile() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.profile != "" {
+		return c.profile
+	}
+	return os.Getenv(ProfileEnv)
+}
+
+// ReadProfile reads the base files and the profile files of a name. The name
+// is a path without extension, for example "config/10-app". Base files are the
+// name with any supported extension (config/10-app.json), profile files are the
+// name with the active profile before the extension (config/10-app.dev.json).
+//
+// Base files are read with the priority in the name, profile files are read
+// with that priority plus one, so they override the base files. Files which do
+// not exist are skipped, but at least one file must exist. If watch is true,
+// the files which are read are watched.
+//
+// Options override the priority of base files and the watching (see
+// ReadOption).
+func (c *Config) ReadProfile(name string, watch bool, opts ...ReadOption) error {
+	var o = newReadOptions(opts)
+	watch = o.getWatch(watch)
+
+	var exts = make([]string, 0, len(extensions))
+	for ext := range extensions {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+
+	var profile = c.Profile()
+	if strings.ContainsAny(profile, "/\\") {
+		return FormatError.Newf("invalid profile %s", profile)
+	}
+
+	var found = false
+	for _, ext := range exts {
+		var priority = o.getPriority(getPriority(name + ext))
+		var filenames = []string{name + ext}
+		if profile != "" {
+			filenames = append(filenames, name+"."+profile+ext)
+		}
+
+		for i, filename := range filenames {
+			if _, err := os.Stat(filename); err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return ConfigError.New(err)
+			}
+
+			found = true
+			if err := c.readWatchedFile(filename, priority+i, watch, o.interval); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !found {
+		return ConfigError.Newf("not found any file of %s (profile %q)",
+			name, profile)
+	}
+
+	return nil
+}
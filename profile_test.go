@@ -0,0 +1,130 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+func TestConfigProfile(t *testing.T) {
+	var cfg = xyconfig.GetConfig(t.Name())
+
+	os.Setenv(xyconfig.ProfileEnv, "staging")
+	defer os.Unsetenv(xyconfig.ProfileEnv)
+	xycond.ExpectEqual(cfg.Profile(), "staging").Test(t)
+
+	cfg.SetProfile("dev")
+	xycond.ExpectEqual(cfg.Profile(), "dev").Test(t)
+
+	cfg.SetProfile("")
+	xycond.ExpectEqual(cfg.Profile(), "staging").Test(t)
+}
+
+func TestConfigReadProfile(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"10-app.json":     `{"host": "localhost", "port": 8080}`,
+		"10-app.dev.json": `{"host": "dev.local"}`,
+		"10-app.prod.ini": "host = prod.example.com",
+		"10-app.env":      "debug=true",
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetProfile("dev")
+	xycond.ExpectNil(cfg.ReadProfile(filepath.Join(dir, "10-app"), false)).Test(t)
+
+	xycond.ExpectEqual(cfg.MustGet("host").MustString(), "dev.local").Test(t)
+	xycond.ExpectEqual(cfg.MustGet("port").MustInt(), 8080).Test(t)
+	xycond.ExpectTrue(cfg.MustGet("debug").MustBool()).Test(t)
+
+	// The profile file has the priority 11, so it can not be overridden by a
+	// value with the priority 10.
+	cfg.Set("host", "other", 10, true)
+	xycond.ExpectEqual(cfg.MustGet("host").MustString(), "dev.local").Test(t)
+}
+
+func TestConfigReadProfileWithEnv(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.json":      `{"host": "localhost"}`,
+		"app.prod.json": `{"host": "prod.example.com"}`,
+	})
+
+	os.Setenv(xyconfig.ProfileEnv, "prod")
+	defer os.Unsetenv(xyconfig.ProfileEnv)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.ReadProfile(filepath.Join(dir, "app"), false)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("host").MustString(), "prod.example.com").Test(t)
+}
+
+func TestConfigReadProfileWithoutProfile(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.json":     `{"host": "localhost"}`,
+		"app.dev.json": `{"host": "dev.local"}`,
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.ReadProfile(filepath.Join(dir, "app"), false)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("host").MustString(), "localhost").Test(t)
+}
+
+func TestConfigReadProfileError(t *testing.T) {
+	var dir = t.TempDir()
+	var cfg = xyconfig.GetConfig(t.Name())
+
+	var err = cfg.ReadProfile(filepath.Join(dir, "app"), false)
+	xycond.ExpectError(err, xyconfig.ConfigError).Test(t)
+
+	cfg.SetProfile("../dev")
+	err = cfg.ReadProfile(filepath.Join(dir, "app"), false)
+	xycond.ExpectError(err, xyconfig.FormatError).Test(t)
+}
+
+func TestConfigReadProfileWithWatching(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.json":     `{"host": "localhost"}`,
+		"app.dev.json": `{"host": "dev.local"}`,
+	})
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	cfg.SetProfile("dev")
+	xycond.ExpectNil(cfg.ReadProfile(filepath.Join(dir, "app"), true)).Test(t)
+
+	writeFiles(t, dir, map[string]string{"app.dev.json": `{"host": "dev2.local"}`})
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("host").MustString() == "dev2.local"
+	})).Test(t)
+
+	// The reloaded profile file keeps overriding the base file.
+	writeFiles(t, dir, map[string]string{"app.json": `{"host": "remote"}`})
+	xycond.ExpectFalse(eventually(func() bool {
+		return cfg.MustGet("host").MustString() == "remote"
+	})).Test(t)
+}
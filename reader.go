@@ -0,0 +1,113 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+)
+
+// stdinSource is the source name of values read from the standard input.
+const stdinSource = "stdin"
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// iniSectionExp matches a section header of INI format.
+var iniSectionExp = regexp.MustCompile(`^\[[^\[\]]+\]$`)
+
+// envLineExp matches a KEY=VALUE line of ENV format.
+var envLineExp = regexp.MustCompile(`^(export\s+)?[A-Za-z_][\w.\-]*\s*=`)
+
+// ReadReader reads the config values from a reader until EOF. If the format is
+// UnknownFormat, it is detected from the content: a JSON object, INI sections
+// or KEY=VALUE lines (which are read as ENV).
+//
+// As ReadBytes, include directives are ignored.
+func (c *Config) ReadReader(format Format, priority int, r io.Reader) error {
+	return c.readReader(format, priority, r, "")
+}
+
+// readReader reads the config values from a reader. The values are marked as
+// read from the source.
+func (c *Config) readReader(format Format, priority int, r io.Reader, source string) error {
+	var data, err = ioutil.ReadAll(r)
+	if err != nil {
+		return ConfigError.New(err)
+	}
+
+	if format == UnknownFormat {
+		if format = detectFormat(data); format == UnknownFormat {
+			return FormatError.New("cannot detect the format of data")
+		}
+	}
+
+	return c.readBytes(format, priority, data, source)
+}
+
+// readStdin reads the config values from the standard input with the priority.
+// If the format is UnknownFormat, it is detected from the content.
+func (c *Config) readStdin(format Format, priority int) error {
+	return c.readReader(format, priority, os.Stdin, stdinSource)
+}
+
+// detectFormat returns the format of data by sniffing its content. It returns
+// UnknownFormat if the content matches no format.
+func detectFormat(data []byte) Format {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, utf8BOM))
+	if len(data) == 0 {
+		return UnknownFormat
+	}
+
+	if data[0] == '{' {
+		if json.Valid(data) {
+			return JSON
+		}
+		return UnknownFormat
+	}
+
+	// Lines of INI format without any section are the same as ENV lines, so
+	// data is only detected as INI if it has a section or INI comments.
+	var format = UnknownFormat
+	var isINI = false
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		switch {
+		case len(line) == 0, line[0] == '#':
+		case line[0] == ';':
+			isINI = true
+		case iniSectionExp.Match(line):
+			return INI
+		case envLineExp.Match(line):
+			format = ENV
+		default:
+			return UnknownFormat
+		}
+	}
+
+	if isINI {
+		return INI
+	}
+	return format
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+func TestConfigReadReader(t *testing.T) {
+	var cfg = xyconfig.GetConfig(t.Name())
+	var r = strings.NewReader(`{"host": "localhost"}`)
+	xycond.ExpectNil(cfg.ReadReader(xyconfig.JSON, 0, r)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("host").MustString(), "localhost").Test(t)
+}
+
+func TestConfigReadReaderDetectFormat(t *testing.T) {
+	var testcases = []struct {
+		data string
+		key  string
+		want string
+	}{
+		{"\n  {\"db\": {\"host\": \"json.local\"}}\n", "db.host", "json.local"},
+		{"# generated\n[db]\nhost = ini.local\n", "db.host", "ini.local"},
+		{"; generated\nhost = ini.local\n", "DEFAULT.host", "ini.local"},
+		{"# generated\nexport HOST=env.local\nPORT=8080\n", "HOST", "env.local"},
+	}
+
+	for i, tc := range testcases {
+		var cfg = xyconfig.GetConfig(t.Name() + string(rune('a'+i)))
+		var r = strings.NewReader(tc.data)
+		xycond.ExpectNil(cfg.ReadReader(xyconfig.UnknownFormat, 0, r)).Test(t)
+		xycond.ExpectEqual(cfg.MustGet(tc.key).MustString(), tc.want).Test(t)
+	}
+}
+
+func TestConfigReadReaderUnknownFormat(t *testing.T) {
+	var cfg = xyconfig.GetConfig(t.Name())
+	for _, data := range []string{"", "  \n", "{invalid", "just some text"} {
+		var err = cfg.ReadReader(xyconfig.UnknownFormat, 0, strings.NewReader(data))
+		xycond.ExpectError(err, xyconfig.FormatError).Test(t)
+	}
+}
+
+func TestConfigReadStdin(t *testing.T) {
+	var dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{"stdin": "[server]\nport = 8080"})
+
+	var f, err = os.Open(filepath.Join(dir, "stdin"))
+	xycond.ExpectNil(err).Test(t)
+	defer f.Close()
+
+	var stdin = os.Stdin
+	os.Stdin = f
+	defer func() { os.Stdin = stdin }()
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.Read("-")).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("server.port").MustInt(), 8080).Test(t)
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SetDebounce sets the time window to coalesce changes of a watched file. A
+// single save often produces many events, the file is only reloaded once with
+// its final content after no event happens in the window. A zero duration
+// (default) reloads the file on every event.
+func (c *Config) SetDebounce(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.debounce = d
+}
+
+// scheduleReload reloads the file after the debounce window. Any change
+// happening in the window postpones the reload.
+func (c *Config) scheduleReload(filename string) {
+	c.lock.Lock()
+	var d = c.debounce
+	if d <= 0 {
+		c.lock.Unlock()
+		c.reloadFile(filename)
+		return
+	}
+	defer c.lock.Unlock()
+
+	if timer, ok := c.reloadTimers[filename]; ok {
+		timer.Reset(d)
+		return
+	}
+
+	c.reloadTimers[filename] = time.AfterFunc(d, func() {
+		c.lock.WLockFunc(func() {
+			delete(c.reloadTimers, filename)
+		})
+		c.reloadFile(filename)
+	})
+}
+
+// reloadFile reloads a watched file. The file is not reloaded if its content
+// is unchanged. Values of the file are removed if the file no longer exists.
+func (c *Config) reloadFile(filename string) {
+	var data, err = ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.lock.WLockFunc(func() {
+				delete(c.fileHashes, filepath.Clean(filename))
+			})
+			c.removeSource(filename)
+			logger.Event("remove-config").Field("filename", filename).Info()
+			return
+		}
+
+		logger.Event("reload-error").
+			Field("filename", filename).Field("error", err).Warning()
+		return
+	}
+
+	if c.isWritten(filename, data) || !c.setFileHash(filename, data) {
+		return
+	}
+
+	// An included file is reloaded with the files including it, so they still
+	// override it.
+	if roots := c.includeRoots(filename); len(roots) > 0 {
+		err = c.reapplyFiles(roots)
+	} else {
+		err = c.applyFile(filename, c.getFilePriority(filename), data, c.isWatchedFile(filename), nil)
+	}
+
+	if err != nil {
+		logger.Event("reload-error").
+			Field("filename", filename).Field("error", err).Warning()
+	} else {
+		logger.Event("reload-config").Field("filename", filename).Info()
+	}
+}
+
+// reapplyFiles reads the files again with their included files.
+func (c *Config) reapplyFiles(filenames []string) error {
+	for _, filename := range filenames {
+		var data, err = ioutil.ReadFile(filename)
+		if err != nil {
+			return ConfigError.New(err)
+		}
+
+		err = c.applyFile(filename, c.getFilePriority(filename), data, c.isWatchedFile(filename), nil)
+		if err != nil {
+			return err
+		}
+		c.setFileHash(filename, data)
+	}
+
+	return nil
+}
+
+// isWatchedFile returns true if the file is watched by the watcher.
+func (c *Config) isWatchedFile(filename string) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var _, watched = c.watchedFiles[filepath.Clean(filename)]
+	return watched
+}
+
+// isWritten returns true if the data was written by Config and the watcher has
+// not seen it yet. It happens when the file is written again before the event
+// of the previous write is handled, the latest content will be seen later.
+func (c *Config) isWritten(filename string, data []byte) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var name = filepath.Clean(filename)
+	var hash = sha256.Sum256(data)
+	var hashes = c.writtenHashes[name]
+	for i := range hashes {
+		if hashes[i] == hash {
+			c.writtenHashes[name] = hashes[i+1:]
+			return true
+		}
+	}
+
+	return false
+}
+
+// setFileHash records the content hash of the file. The return value is false
+// if the content is unchanged since the last record. A different content means
+// the file was changed by others, so the pending writes of Config are
+// forgotten.
+func (c *Config) setFileHash(filename string, data []byte) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var name = filepath.Clean(filename)
+	var hash = sha256.Sum256(data)
+	if old, ok := c.fileHashes[name]; ok && old == hash {
+		return false
+	}
+
+	c.fileHashes[name] = hash
+	delete(c.writtenHashes, name)
+	return true
+}
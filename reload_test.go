@@ -0,0 +1,121 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+func TestConfigSetDebounce(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "config.json")
+	ioutil.WriteFile(filename, []byte(`{"foo": 0}`), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	cfg.SetDebounce(100 * time.Millisecond)
+	xycond.ExpectNil(cfg.ReadFile(filename, true)).Test(t)
+
+	var count int32
+	cfg.AddHook("foo", func(e xyconfig.Event) { atomic.AddInt32(&count, 1) })
+
+	for i := 1; i <= 5; i++ {
+		ioutil.WriteFile(filename, []byte(fmt.Sprintf(`{"foo": %d}`, i)), 0644)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustInt() == 5
+	})).Test(t)
+	time.Sleep(200 * time.Millisecond)
+	xycond.ExpectEqual(atomic.LoadInt32(&count), int32(1)).Test(t)
+}
+
+func TestConfigReloadUnchangedFile(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "config.json")
+	ioutil.WriteFile(filename, []byte(`{"foo": "bar", "buzz": 1}`), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadFile(filename, true)).Test(t)
+	cfg.Set("foo", "manual", 0, true)
+
+	// Rewriting the same content does not reload the file.
+	ioutil.WriteFile(filename, []byte(`{"foo": "bar", "buzz": 1}`), 0644)
+	time.Sleep(100 * time.Millisecond)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "manual").Test(t)
+
+	ioutil.WriteFile(filename, []byte(`{"foo": "bar", "buzz": 2}`), 0644)
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustString() == "bar"
+	})).Test(t)
+}
+
+func TestConfigSetOverrideFileWithWatching(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "override.json")
+	ioutil.WriteFile(filename, []byte(`{"foo": "bar"}`), 0644)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadFile(filename, true)).Test(t)
+	xycond.ExpectNil(cfg.SetOverrideFile(filename, 0)).Test(t)
+
+	var count int32
+	cfg.AddHook("", func(e xyconfig.Event) { atomic.AddInt32(&count, 1) })
+
+	cfg.Set("foo", "buzz", 0, true)
+	cfg.Set("foo", "bizz", 0, true)
+	time.Sleep(100 * time.Millisecond)
+
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bizz").Test(t)
+	xycond.ExpectEqual(atomic.LoadInt32(&count), int32(2)).Test(t)
+}
+
+func TestConfigSetOverrideFileExternalEdit(t *testing.T) {
+	var filename = filepath.Join(t.TempDir(), "override.json")
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.SetOverrideFile(filename, 50)).Test(t)
+
+	// The file is written while it is not watched.
+	cfg.Set("foo", "bar", 50, true)
+	var old, err = ioutil.ReadFile(filename)
+	xycond.ExpectNil(err).Test(t)
+	cfg.Set("foo", "buzz", 50, true)
+
+	err = cfg.Read(filename, xyconfig.WithPriority(60), xyconfig.WithWatch())
+	xycond.ExpectNil(err).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "buzz").Test(t)
+
+	// An external edit back to a content written by Config before is reloaded.
+	ioutil.WriteFile(filename, old, 0644)
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustString() == "bar"
+	})).Test(t)
+}
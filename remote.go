@@ -0,0 +1,130 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"context"
+	"time"
+)
+
+// remoteVersion identifies the version of a remote object which was last read.
+type remoteVersion struct {
+	etag         string
+	lastModified string
+}
+
+// remoteObject is a fetched remote object. Its data is nil if the object has
+// not changed since the last read.
+type remoteObject struct {
+	data    []byte
+	format  Format
+	version remoteVersion
+}
+
+// fetchFunc fetches a remote object if it has changed since the version.
+type fetchFunc func(version remoteVersion) (remoteObject, error)
+
+// readRemote reads a remote source by the fetch function. If d is not zero, it
+// reads the source again after the duration, or after a backoff if the fetch
+// fails.
+//
+// When watching, a failed fetch is not returned because it will be retried.
+// If the source has never been fetched, its cached copy is read instead.
+//
+// If the format of the fetched object is unknown, it is detected from the
+// content.
+func (c *Config) readRemote(source string, priority int, d time.Duration, fetch fetchFunc) error {
+	c.lock.RLock()
+	var version = c.versions[source]
+	c.lock.RUnlock()
+
+	var object, fetchErr = fetch(version)
+	var err error
+	switch {
+	case fetchErr != nil:
+		c.reportStatus(source, fetchErr)
+		if c.loadCache(source, priority) {
+			fetchErr = nil
+		}
+	case object.data != nil:
+		if object.format == UnknownFormat {
+			object.format = detectFormat(object.data)
+		}
+
+		err = c.readBytes(object.format, priority, object.data, source)
+		if err == nil {
+			c.lock.WLockFunc(func() {
+				c.versions[source] = object.version
+			})
+			c.saveCache(source, object.format, object.data)
+		}
+		c.reportStatus(source, err)
+	default:
+		c.reportStatus(source, nil)
+	}
+
+	if d != 0 {
+		var delay = c.nextFetch(source, d)
+		c.lock.Lock()
+		c.timerWatchers[source] = time.AfterFunc(delay, func() {
+			c.readRemote(source, priority, d, fetch)
+		})
+		c.lock.Unlock()
+
+		if fetchErr != nil {
+			return nil
+		}
+	}
+
+	if fetchErr != nil {
+		return fetchErr
+	}
+	return err
+}
+
+// stream watches a remote source until it is stopped by UnWatch or
+// CloseWatcher.
+type stream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Stop stops the stream. The return value is false if the stream has already
+// been stopped.
+func (s *stream) Stop() bool {
+	var active = s.ctx.Err() == nil
+	s.cancel()
+	return active
+}
+
+// wait waits for the duration. The return value is false if the stream is
+// stopped while waiting.
+func (s *stream) wait(d time.Duration) bool {
+	var timer = time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// ReadS3 reads a file from AWS S3 bucket and watch for their changes every
+// duration. Set the duration as zero if no need to watch the change.
+//
+// The object is requested with the ETag of the last read, so an unchanged
+// object is neither downloaded nor parsed again. When watching, a failed fetch
+// is retried with an exponential backoff, see Status for the health of the
+// source. See SetCacheDir to load a cached copy when the object can not be
+// fetched.
+//
+// The format is chosen by the extension of the object key. If the extension is
+// unknown, the format is detected from the content. Options override the
+// format, the priority and the interval (see ReadOption).
+//
+// By default, you must provide the aws credentials in ~/.aws/credentials and
+// the AWS_REGION is required. Use SetS3Options or SetS3Client to customize the
+// client.
+func (c *Config) ReadS3(url string, d time.Duration, opts ...ReadOption) error {
+	var o = newReadOptions(opts)
+	c.setSourceFormat(url, o.format)
+	return c.readS3(url, o.getPriority(getPriority(url)), o.getInterval(d))
+}
+
+// readS3 reads a file from AWS S3 bucket with the priority.
+func (c *Config) readS3(url string, priority int, d time.Duration) error {
+	var fileFormat = c.getSourceFormat(url, url)
+	var bucket, item, err = parseS3URL(url)
+	if err != nil {
+		return err
+	}
+
+	return c.readRemote(url, priority, d,
+		func(version remoteVersion) (remoteObject, error) {
+			var client, err = c.getS3Client()
+			if err != nil {
+				return remoteObject{}, err
+			}
+			return getS3Object(client, bucket, item, fileFormat, version)
+		})
+}
+
+// S3Options contains the options to create the client reading S3 objects. The
+// zero values fall back to the shared aws config.
+type S3Options struct {
+	// Endpoint is the url of a S3 compatible service, such as MinIO.
+	Endpoint string
+
+	// Region is the region of buckets.
+	Region string
+
+	// Credentials provides the credentials to access buckets, for example
+	// credentials.NewStaticCredentials(id, secret, "").
+	Credentials *credentials.Credentials
+
+	// ForcePathStyle uses the path-style addressing (http://host/bucket/key)
+	// instead of the virtual-hosted style (http://bucket.host/key).
+	ForcePathStyle bool
+}
+
+// SetS3Options creates the client reading S3 objects with the options.
+func (c *Config) SetS3Options(opts S3Options) error {
+	var cfg = aws.NewConfig()
+	if opts.Endpoint != "" {
+		cfg = cfg.WithEndpoint(opts.Endpoint)
+	}
+	if opts.Region != "" {
+		cfg = cfg.WithRegion(opts.Region)
+	}
+	if opts.Credentials != nil {
+		cfg = cfg.WithCredentials(opts.Credentials)
+	}
+	if opts.ForcePathStyle {
+		cfg = cfg.WithS3ForcePathStyle(true)
+	}
+
+	var sess, err = session.NewSessionWithOptions(session.Options{
+		Config:            *cfg,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return ConfigError.New(err)
+	}
+
+	c.SetS3Client(s3.New(sess))
+	return nil
+}
+
+// SetS3Client sets the client reading S3 objects. It is useful to use a pre-
+// built client or a stand-in in tests.
+func (c *Config) SetS3Client(client s3iface.S3API) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.s3Client = client
+}
+
+// getS3Client returns the client reading S3 objects. If no client is set, it
+// creates a client from the shared aws config.
+func (c *Config) getS3Client() (s3iface.S3API, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.s3Client != nil {
+		return c.s3Client, nil
+	}
+
+	var sess, err = session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, ConfigError.New(err)
+	}
+
+	c.s3Client = s3.New(sess)
+	return c.s3Client, nil
+}
+
+// getS3Object downloads the object if it has changed since the version.
+func getS3Object(
+	client s3iface.S3API, bucket, item string, format Format, version remoteVersion,
+) (remoteObject, error) {
+	var input = &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(item),
+	}
+
+	if version.etag != "" {
+		input.IfNoneMatch = aws.String(version.etag)
+	}
+
+	var output, err = client.GetObject(input)
+	if err != nil {
+		if reqErr, ok := err.(awserr.RequestFailure); ok &&
+			reqErr.StatusCode() == http.StatusNotModified {
+			return remoteObject{}, nil
+		}
+		return remoteObject{}, ConfigError.New(err)
+	}
+	defer output.Body.Close()
+
+	data, err := ioutil.ReadAll(output.Body)
+	if err != nil {
+		return remoteObject{}, ConfigError.New(err)
+	}
+
+	return remoteObject{
+		data:    data,
+		format:  format,
+		version: remoteVersion{etag: aws.StringValue(output.ETag)},
+	}, nil
+}
+
+// parseS3URL returns the bucket and the item of a s3 url.
+func parseS3URL(url string) (string, string, error) {
+	if !strings.HasPrefix(url, "s3://") {
+		return "", "", FormatError.Newf("can not parse the s3 url %s", url)
+	}
+
+	var path = url[5:]
+	var bucket, item, found = strings.Cut(path, "/")
+	if !found {
+		return "", "", FormatError.Newf("not found item in path %s", path)
+	}
+
+	return bucket, item, nil
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+// fakeS3 is a stand-in of S3 storing objects in memory.
+type fakeS3 struct {
+	s3iface.S3API
+
+	lock    sync.Mutex
+	objects map[string]string
+	etags   map[string]string
+	gets    int
+	fetches int
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string]string{}, etags: map[string]string{}}
+}
+
+func (f *fakeS3) put(bucket, key, content, etag string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.objects[bucket+"/"+key] = content
+	f.etags[bucket+"/"+key] = etag
+}
+
+func (f *fakeS3) counts() (int, int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.gets, f.fetches
+}
+
+func (f *fakeS3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.gets++
+	var name = aws.StringValue(input.Bucket) + "/" + aws.StringValue(input.Key)
+	var content, ok = f.objects[name]
+	if !ok {
+		return nil, awserr.NewRequestFailure(
+			awserr.New(s3.ErrCodeNoSuchKey, "not found", nil), http.StatusNotFound, "")
+	}
+
+	if aws.StringValue(input.IfNoneMatch) == f.etags[name] {
+		return nil, awserr.NewRequestFailure(
+			awserr.New("NotModified", "not modified", nil), http.StatusNotModified, "")
+	}
+
+	f.fetches++
+	return &s3.GetObjectOutput{
+		Body: ioutil.NopCloser(bytes.NewBufferString(content)),
+		ETag: aws.String(f.etags[name]),
+	}, nil
+}
+
+func TestConfigReadS3WithClient(t *testing.T) {
+	var client = newFakeS3()
+	client.put("bucket", "10-app.json", `{"foo": "bar"}`, `"1"`)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetS3Client(client)
+	xycond.ExpectNil(cfg.ReadS3("s3://bucket/10-app.json", 0)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+
+	var err = cfg.ReadS3("s3://bucket/not-exist.json", 0)
+	xycond.ExpectError(err, xyconfig.ConfigError).Test(t)
+}
+
+func TestConfigReadS3NotModified(t *testing.T) {
+	var client = newFakeS3()
+	client.put("bucket", "app.json", `{"foo": "bar"}`, `"1"`)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetS3Client(client)
+
+	var changes = 0
+	cfg.AddHook("foo", func(e xyconfig.Event) { changes++ })
+
+	xycond.ExpectNil(cfg.ReadS3("s3://bucket/app.json", 0)).Test(t)
+	xycond.ExpectNil(cfg.ReadS3("s3://bucket/app.json", 0)).Test(t)
+
+	var gets, fetches = client.counts()
+	xycond.ExpectEqual(gets, 2).Test(t)
+	xycond.ExpectEqual(fetches, 1).Test(t)
+	xycond.ExpectEqual(changes, 1).Test(t)
+
+	client.put("bucket", "app.json", `{"foo": "buzz"}`, `"2"`)
+	xycond.ExpectNil(cfg.ReadS3("s3://bucket/app.json", 0)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "buzz").Test(t)
+	xycond.ExpectEqual(changes, 2).Test(t)
+}
+
+func TestConfigReadS3WithChange(t *testing.T) {
+	var client = newFakeS3()
+	client.put("bucket", "app.json", `{"foo": "bar"}`, `"1"`)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	cfg.SetS3Client(client)
+
+	xycond.ExpectNil(cfg.ReadS3("s3://bucket/app.json", 10*time.Millisecond)).Test(t)
+	client.put("bucket", "app.json", `{"foo": "buzz"}`, `"2"`)
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustString() == "buzz"
+	})).Test(t)
+}
+
+func TestConfigSetS3Options(t *testing.T) {
+	var server = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/bucket/app.ini" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set("ETag", `"1"`)
+			w.Write([]byte("foo = bar"))
+		}))
+	defer server.Close()
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	var err = cfg.SetS3Options(xyconfig.S3Options{
+		Endpoint:       server.URL,
+		Region:         "us-east-1",
+		Credentials:    credentials.NewStaticCredentials("id", "secret", ""),
+		ForcePathStyle: true,
+	})
+	xycond.ExpectNil(err).Test(t)
+
+	xycond.ExpectNil(cfg.ReadS3("s3://bucket/app.ini", 0)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("DEFAULT.foo").MustString(), "bar").Test(t)
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/xybor-x/xylock"
+)
+
+// redacted is displayed in place of sensitive values.
+const redacted = "******"
+
+// sensitiveLock avoids race condition of sensitivePatterns.
+var sensitiveLock = &xylock.RWLock{}
+
+// sensitivePatterns contains patterns of full keys whose values are sensitive.
+var sensitivePatterns []*regexp.Regexp
+
+// AddSensitive marks keys matching the patterns as sensitive. Values of these
+// keys are displayed as ****** by Value.String, Event.String, and ToMap, but
+// typed accessors such as MustString still return the real values.
+//
+// Patterns are relative to the Config. The wildcard * matches any sequence of
+// characters (including dots), the wildcard ? matches any single character.
+//
+// For example:
+//    config.AddSensitive("*.password", "*token*", "db.dsn")
+func (c *Config) AddSensitive(patterns ...string) {
+	var exps []*regexp.Regexp
+	for _, p := range patterns {
+		exps = append(exps, compileKeyPattern(c.name+"."+p))
+	}
+
+	sensitiveLock.WLockFunc(func() {
+		sensitivePatterns = append(sensitivePatterns, exps...)
+	})
+}
+
+// IsSensitive returns true if the key is marked as sensitive.
+func (c *Config) IsSensitive(key string) bool {
+	return isSensitive(c.name + "." + key)
+}
+
+// String returns the string representation of Event. Sensitive values are
+// redacted.
+func (e Event) String() string {
+	return fmt.Sprintf("%s: %s -> %s", e.Key, e.Old, e.New)
+}
+
+// isSensitive returns true if the full key matches any sensitive pattern.
+func isSensitive(fullkey string) bool {
+	return sensitiveLock.RLockFunc(func() any {
+		for _, exp := range sensitivePatterns {
+			if exp.MatchString(fullkey) {
+				return true
+			}
+		}
+		return false
+	}).(bool)
+}
+
+// compileKeyPattern converts a wildcard pattern to a regular expression.
+func compileKeyPattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+
+	// All special characters are quoted, so the expression is always valid.
+	return regexp.MustCompile(b.String())
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package server exposes a xyconfig.Config over HTTP, so one process can be
+// the config hub of other services.
+//
+// A path is a dot-separated key with slashes, for example /db/host is the key
+// "db.host", and / is the whole Config:
+//
+//    GET  /db?format=ini      returns the sub-Config under the format (json,
+//                             ini or env), with ETag and X-Config-Version
+//                             headers.
+//    GET  /db (Accept: text/event-stream)
+//                             streams changes as Server-Sent Events which can
+//                             be read by Config.ReadSSE.
+//    PUT  /db/host            sets the JSON value of the body, it requires the
+//                             bearer token of Options.
+//
+// Sensitive values are always redacted.
+package server
@@ -0,0 +1,343 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ini/ini"
+	"github.com/xybor-x/xyconfig"
+)
+
+// eventBuffer is the number of changes buffered for an event stream. A client
+// which falls behind is disconnected and resyncs after reconnecting.
+const eventBuffer = 256
+
+// keepAliveInterval is the time interval to send comments to event streams,
+// so idle connections are not closed by proxies.
+const keepAliveInterval = 30 * time.Second
+
+// formats maps the format query parameter to formats.
+var formats = map[string]xyconfig.Format{
+	"json": xyconfig.JSON,
+	"ini":  xyconfig.INI,
+	"env":  xyconfig.ENV,
+}
+
+// contentTypes maps formats to their content types.
+var contentTypes = map[xyconfig.Format]string{
+	xyconfig.JSON: "application/json",
+	xyconfig.INI:  "text/x-ini; charset=utf-8",
+	xyconfig.ENV:  "text/x-env; charset=utf-8",
+}
+
+// Options contains the options of Server.
+type Options struct {
+	// Token is the bearer token required by PUT requests. PUT requests are
+	// forbidden if it is empty.
+	Token string
+
+	// Priority is the priority of values set by PUT requests.
+	Priority int
+}
+
+// Server is a http.Handler exposing a Config.
+type Server struct {
+	config  *xyconfig.Config
+	options Options
+}
+
+// New creates a Server exposing the Config.
+func New(config *xyconfig.Config, options Options) *Server {
+	return &Server{config: config, options: options}
+}
+
+// ServeHTTP handles a request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var key = strings.ReplaceAll(strings.Trim(r.URL.Path, "/"), "/", ".")
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			s.serveEvents(w, r, key)
+		} else {
+			s.serveValues(w, r, key)
+		}
+	case http.MethodPut:
+		s.serveSet(w, r, key)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// serveValues writes the values of the key under the requested format.
+func (s *Server) serveValues(w http.ResponseWriter, r *http.Request, key string) {
+	var name = r.URL.Query().Get("format")
+	if name == "" {
+		name = "json"
+	}
+
+	var format, ok = formats[name]
+	if !ok {
+		http.Error(w, "unknown format "+name, http.StatusBadRequest)
+		return
+	}
+
+	var version = s.config.Version()
+	values, ok := s.lookup(key)
+	if !ok {
+		http.Error(w, "not found "+key, http.StatusNotFound)
+		return
+	}
+
+	// INI keys outside any section are read as the DEFAULT sub-Config, so
+	// top-level values of the sub-Config are written there.
+	if format == xyconfig.INI {
+		values = defaultSection(values)
+	}
+
+	var data, err = xyconfig.MarshalMap(format, values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var hash = sha256.Sum256(data)
+	var etag = `"` + hex.EncodeToString(hash[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("X-Config-Version", strconv.FormatUint(version, 10))
+	w.Header().Set("Content-Type", contentTypes[format])
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Write(data)
+}
+
+// defaultSection moves the non-map values of m to the DEFAULT map.
+func defaultSection(m map[string]any) map[string]any {
+	var result = make(map[string]any, len(m))
+	var section = make(map[string]any)
+	if d, ok := m[ini.DefaultSection].(map[string]any); ok {
+		for k, v := range d {
+			section[k] = v
+		}
+	}
+
+	for k, v := range m {
+		if _, ok := v.(map[string]any); ok {
+			result[k] = v
+		} else {
+			section[k] = v
+		}
+	}
+
+	if len(section) > 0 {
+		result[ini.DefaultSection] = section
+	}
+	return result
+}
+
+// serveSet sets the JSON value of the request body to the key. If the value is
+// an object, every value in it is set to its sub key.
+func (s *Server) serveSet(w http.ResponseWriter, r *http.Request, key string) {
+	if s.options.Token == "" {
+		http.Error(w, "read-only config", http.StatusForbidden)
+		return
+	}
+
+	var token = []byte("Bearer " + s.options.Token)
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), token) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var value any
+	if err := json.NewDecoder(r.Body).Decode(&value); err != nil {
+		http.Error(w, "invalid json body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var values = make(map[string]any)
+	flatten(key, value, values)
+	if _, ok := values[""]; ok {
+		http.Error(w, "the root value must be an object", http.StatusBadRequest)
+		return
+	}
+
+	for k, v := range values {
+		s.config.Set(k, v, s.options.Priority, true)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveEvents streams changes of values under the key. The stream starts with
+// a snapshot of all values, then sends a change or delete event for every
+// changed key.
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request, key string) {
+	var flusher, ok = w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var prefix = s.config.Name() + "."
+	if key != "" {
+		prefix += key + "."
+	}
+
+	var changes = make(chan string, eventBuffer)
+	var overflow = make(chan struct{})
+	var once sync.Once
+	var unsubscribe = s.config.Subscribe(func(e xyconfig.Event) {
+		if !strings.HasPrefix(e.Key, prefix) {
+			return
+		}
+
+		select {
+		case changes <- strings.TrimPrefix(e.Key, prefix):
+		default:
+			once.Do(func() { close(overflow) })
+		}
+	})
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	var snapshot, found = s.lookup(key)
+	if !found {
+		snapshot = map[string]any{}
+	}
+	s.writeEvent(w, xyconfig.SnapshotEvent, snapshot)
+	flusher.Flush()
+
+	var ticker = time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-overflow:
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case subkey := <-changes:
+			var value, ok = s.get(join(key, subkey))
+			if ok {
+				s.writeEvent(w, xyconfig.ChangeEvent, map[string]any{"key": subkey, "value": value})
+			} else {
+				s.writeEvent(w, xyconfig.DeleteEvent, map[string]any{"key": subkey})
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes an event whose data is the JSON of the value. The id of
+// the event is the version of the Config.
+func (s *Server) writeEvent(w http.ResponseWriter, event string, value any) {
+	var data, err = json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(map[string]any{"error": err.Error()})
+		event = "error"
+	}
+
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", s.config.Version(), event, data)
+}
+
+// lookup returns the values of the key as a map. The value of a key which is
+// not a sub-Config is returned as a map of its last dot-separated part.
+func (s *Server) lookup(key string) (map[string]any, bool) {
+	var value, ok = s.get(key)
+	if !ok {
+		return nil, false
+	}
+
+	if m, ok := value.(map[string]any); ok {
+		return m, true
+	}
+
+	return map[string]any{key[strings.LastIndex(key, ".")+1:]: value}, true
+}
+
+// get returns the redacted value of the key. The value of a sub-Config is a
+// map.
+func (s *Server) get(key string) (any, bool) {
+	if key == "" {
+		return s.config.ToMap(), true
+	}
+
+	var parent, last = s.config, key
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		var value, ok = s.config.Get(key[:i])
+		if !ok {
+			return nil, false
+		}
+
+		parent, ok = value.AsConfig()
+		if !ok {
+			return nil, false
+		}
+		last = key[i+1:]
+	}
+
+	var value, ok = parent.ToMap()[last]
+	return value, ok
+}
+
+// flatten stores leaf values of an object to the result with dot-separated
+// keys.
+func flatten(key string, value any, result map[string]any) {
+	var m, ok = value.(map[string]any)
+	if !ok {
+		result[key] = value
+		return
+	}
+
+	for k, v := range m {
+		flatten(join(key, k), v, result)
+	}
+}
+
+// join joins two dot-separated keys.
+func join(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "." + b
+}
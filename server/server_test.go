@@ -0,0 +1,199 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package server_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+	"github.com/xybor-x/xyconfig/server"
+)
+
+func eventually(cond func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+func request(t *testing.T, method, url string, header http.Header, body string) (*http.Response, string) {
+	var req, err = http.NewRequest(method, url, bytes.NewBufferString(body))
+	xycond.ExpectNil(err).Test(t)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	xycond.ExpectNil(err).Test(t)
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	xycond.ExpectNil(err).Test(t)
+	return resp, string(data)
+}
+
+func newHub(t *testing.T, options server.Options) (*xyconfig.Config, *httptest.Server) {
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.ReadJSON(0, []byte(`{"name": "app", "db": {"host": "a", "port": 1, "password": "secret"}}`))
+	cfg.AddSensitive("db.password")
+	return cfg, httptest.NewServer(server.New(cfg, options))
+}
+
+func TestServerGet(t *testing.T) {
+	var cfg, hub = newHub(t, server.Options{})
+	defer hub.Close()
+
+	var resp, body = request(t, http.MethodGet, hub.URL, nil, "")
+	xycond.ExpectEqual(resp.StatusCode, http.StatusOK).Test(t)
+	xycond.ExpectEqual(resp.Header.Get("Content-Type"), "application/json").Test(t)
+	xycond.ExpectEqual(resp.Header.Get("X-Config-Version"), "4").Test(t)
+
+	var other = xyconfig.GetConfig(t.Name() + "-other")
+	xycond.ExpectNil(other.ReadJSON(0, []byte(body))).Test(t)
+	xycond.ExpectEqual(other.MustGet("db.host").MustString(), "a").Test(t)
+	xycond.ExpectEqual(other.MustGet("db.password").MustString(), "******").Test(t)
+	xycond.ExpectEqual(other.MustGet("name").MustString(), "app").Test(t)
+
+	resp, body = request(t, http.MethodGet, hub.URL+"/db?format=ini", nil, "")
+	xycond.ExpectEqual(resp.StatusCode, http.StatusOK).Test(t)
+	xycond.ExpectEqual(body, "host     = a\npassword = ******\nport     = 1\n").Test(t)
+
+	resp, body = request(t, http.MethodGet, hub.URL+"/db/port?format=env", nil, "")
+	xycond.ExpectEqual(resp.StatusCode, http.StatusOK).Test(t)
+	xycond.ExpectEqual(body, "port=1\n").Test(t)
+
+	cfg.Set("db.port", 2, 0, true)
+	resp, _ = request(t, http.MethodGet, hub.URL+"/db/port", nil, "")
+	xycond.ExpectEqual(resp.Header.Get("X-Config-Version"), "5").Test(t)
+}
+
+func TestServerGetError(t *testing.T) {
+	var _, hub = newHub(t, server.Options{})
+	defer hub.Close()
+
+	var resp, _ = request(t, http.MethodGet, hub.URL+"/db/user", nil, "")
+	xycond.ExpectEqual(resp.StatusCode, http.StatusNotFound).Test(t)
+
+	resp, _ = request(t, http.MethodGet, hub.URL+"/name/first", nil, "")
+	xycond.ExpectEqual(resp.StatusCode, http.StatusNotFound).Test(t)
+
+	resp, _ = request(t, http.MethodGet, hub.URL+"/db?format=yaml", nil, "")
+	xycond.ExpectEqual(resp.StatusCode, http.StatusBadRequest).Test(t)
+
+	resp, _ = request(t, http.MethodDelete, hub.URL+"/db", nil, "")
+	xycond.ExpectEqual(resp.StatusCode, http.StatusMethodNotAllowed).Test(t)
+}
+
+func TestServerETag(t *testing.T) {
+	var cfg, hub = newHub(t, server.Options{})
+	defer hub.Close()
+
+	var resp, _ = request(t, http.MethodGet, hub.URL+"/db", nil, "")
+	var etag = resp.Header.Get("ETag")
+	xycond.ExpectNotEqual(etag, "").Test(t)
+
+	var header = http.Header{"If-None-Match": []string{etag}}
+	resp, _ = request(t, http.MethodGet, hub.URL+"/db", header, "")
+	xycond.ExpectEqual(resp.StatusCode, http.StatusNotModified).Test(t)
+
+	cfg.Set("db.host", "b", 0, true)
+	resp, _ = request(t, http.MethodGet, hub.URL+"/db", header, "")
+	xycond.ExpectEqual(resp.StatusCode, http.StatusOK).Test(t)
+}
+
+func TestServerPut(t *testing.T) {
+	var cfg, hub = newHub(t, server.Options{Token: "token", Priority: 10})
+	defer hub.Close()
+
+	var resp, _ = request(t, http.MethodPut, hub.URL+"/db/host", nil, `"b"`)
+	xycond.ExpectEqual(resp.StatusCode, http.StatusUnauthorized).Test(t)
+
+	var header = http.Header{"Authorization": []string{"Bearer token"}}
+	resp, _ = request(t, http.MethodPut, hub.URL+"/db/host", header, `"b"`)
+	xycond.ExpectEqual(resp.StatusCode, http.StatusNoContent).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("db.host").MustString(), "b").Test(t)
+
+	resp, _ = request(t, http.MethodPut, hub.URL+"/", header, `{"db": {"port": 2}, "debug": true}`)
+	xycond.ExpectEqual(resp.StatusCode, http.StatusNoContent).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("db.port").MustInt(), 2).Test(t)
+	xycond.ExpectTrue(cfg.MustGet("debug").MustBool()).Test(t)
+
+	// Values are set with the priority of options.
+	cfg.Set("db.port", 3, 5, true)
+	xycond.ExpectEqual(cfg.MustGet("db.port").MustInt(), 2).Test(t)
+
+	resp, _ = request(t, http.MethodPut, hub.URL+"/", header, `1`)
+	xycond.ExpectEqual(resp.StatusCode, http.StatusBadRequest).Test(t)
+
+	resp, _ = request(t, http.MethodPut, hub.URL+"/db", header, `{`)
+	xycond.ExpectEqual(resp.StatusCode, http.StatusBadRequest).Test(t)
+}
+
+func TestServerPutReadOnly(t *testing.T) {
+	var _, hub = newHub(t, server.Options{})
+	defer hub.Close()
+
+	var header = http.Header{"Authorization": []string{"Bearer "}}
+	var resp, _ = request(t, http.MethodPut, hub.URL+"/db/host", header, `"b"`)
+	xycond.ExpectEqual(resp.StatusCode, http.StatusForbidden).Test(t)
+}
+
+func TestServerEvents(t *testing.T) {
+	var cfg, hub = newHub(t, server.Options{})
+	defer hub.Close()
+
+	var filename = filepath.Join(t.TempDir(), "extra.json")
+	xycond.ExpectNil(ioutil.WriteFile(filename, []byte(`{"db": {"user": "admin"}}`), 0644)).Test(t)
+	xycond.ExpectNil(cfg.ReadFile(filename, true)).Test(t)
+	defer cfg.CloseWatcher()
+
+	var sidecar = xyconfig.GetConfig(t.Name() + "-sidecar")
+	defer sidecar.CloseWatcher()
+	xycond.ExpectNil(sidecar.ReadSSE(hub.URL+"/db", 0)).Test(t)
+	xycond.ExpectEqual(sidecar.MustGet("host").MustString(), "a").Test(t)
+	xycond.ExpectEqual(sidecar.MustGet("user").MustString(), "admin").Test(t)
+	xycond.ExpectEqual(sidecar.MustGet("password").MustString(), "******").Test(t)
+
+	cfg.Set("db.host", "b", 0, true)
+	cfg.Set("name", "other", 0, true)
+	xycond.ExpectTrue(eventually(func() bool {
+		return sidecar.MustGet("host").MustString() == "b"
+	})).Test(t)
+	var _, ok = sidecar.Get("name")
+	xycond.ExpectFalse(ok).Test(t)
+
+	os.Remove(filename)
+	xycond.ExpectTrue(eventually(func() bool {
+		var _, ok = sidecar.Get("user")
+		return !ok
+	})).Test(t)
+}
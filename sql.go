@@ -0,0 +1,237 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"database/sql"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// sqlIdentifierExp matches valid table names.
+var sqlIdentifierExp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SQLDialect describes how the queries of ReadSQL quote identifiers and bind
+// parameters.
+type SQLDialect struct {
+	// Quote returns the quoted identifier.
+	Quote func(name string) string
+
+	// Placeholder returns the placeholder of the n-th parameter, starting from
+	// 1.
+	Placeholder func(n int) string
+}
+
+// Supported SQL dialects.
+var (
+	// MySQLDialect quotes identifiers with backticks and uses ? placeholders.
+	// It is the default dialect.
+	MySQLDialect = SQLDialect{
+		Quote:       func(name string) string { return "`" + name + "`" },
+		Placeholder: func(int) string { return "?" },
+	}
+
+	// SQLiteDialect quotes identifiers with double quotes and uses ?
+	// placeholders.
+	SQLiteDialect = SQLDialect{
+		Quote:       func(name string) string { return `"` + name + `"` },
+		Placeholder: func(int) string { return "?" },
+	}
+
+	// PostgreSQLDialect quotes identifiers with double quotes and uses $1, $2,
+	// ... placeholders.
+	PostgreSQLDialect = SQLDialect{
+		Quote:       func(name string) string { return `"` + name + `"` },
+		Placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+	}
+
+	// SQLServerDialect quotes identifiers with brackets and uses @p1, @p2, ...
+	// placeholders.
+	SQLServerDialect = SQLDialect{
+		Quote:       func(name string) string { return "[" + name + "]" },
+		Placeholder: func(n int) string { return "@p" + strconv.Itoa(n) },
+	}
+)
+
+// SetSQLDialect sets the dialect of the queries of later ReadSQL calls. Missing
+// functions of the dialect are taken from MySQLDialect.
+func (c *Config) SetSQLDialect(dialect SQLDialect) {
+	if dialect.Quote == nil {
+		dialect.Quote = MySQLDialect.Quote
+	}
+	if dialect.Placeholder == nil {
+		dialect.Placeholder = MySQLDialect.Placeholder
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.sqlDialect = dialect
+}
+
+// sqlSource is a table of settings being read.
+type sqlSource struct {
+	db       *sql.DB
+	table    string
+	source   string
+	priority int
+	dialect  SQLDialect
+
+	// lastSync is the greatest updated_at of read rows, it is nil before the
+	// first read.
+	lastSync any
+}
+
+// ReadSQL reads the settings of a table with the columns key, value and
+// updated_at, and polls for their changes every duration. Set the duration as
+// zero if no need to watch the change.
+//
+// Keys are dot-separated keys. Values are strings read non-strictly, as ENV
+// does, rows with NULL values are ignored. When polling, only rows whose
+// updated_at is not older than the last read are read again, and values of
+// deleted rows are removed. Failed polls are retried with an exponential
+// backoff.
+//
+// The queries are written in the dialect set by SetSQLDialect, which is
+// MySQLDialect by default. The source is named "sql:" followed by the table,
+// use this name with Status and UnWatch.
+func (c *Config) ReadSQL(db *sql.DB, table string, priority int, d time.Duration) error {
+	if !sqlIdentifierExp.MatchString(table) {
+		return FormatError.Newf("invalid table name %s", table)
+	}
+
+	c.lock.RLock()
+	var dialect = c.sqlDialect
+	c.lock.RUnlock()
+
+	return c.pollSQL(&sqlSource{
+		db:       db,
+		table:    table,
+		source:   "sql:" + table,
+		priority: priority,
+		dialect:  dialect,
+	}, d)
+}
+
+// pollSQL reads the changes of the table, then schedules the next poll if d is
+// not zero. When polling, failures are not returned because they are retried.
+func (c *Config) pollSQL(src *sqlSource, d time.Duration) error {
+	var err = c.syncSQL(src)
+	c.reportStatus(src.source, err)
+
+	if d == 0 {
+		return err
+	}
+
+	var delay = c.nextFetch(src.source, d)
+	c.lock.Lock()
+	c.timerWatchers[src.source] = time.AfterFunc(delay, func() { c.pollSQL(src, d) })
+	c.lock.Unlock()
+
+	return nil
+}
+
+// syncSQL reads the rows updated since the last read and removes the values of
+// deleted rows.
+func (c *Config) syncSQL(src *sqlSource) error {
+	var q = src.dialect.Quote
+	var query = "SELECT " + q("key") + ", " + q("value") + ", " + q("updated_at") +
+		" FROM " + q(src.table)
+	var args []any
+	if src.lastSync != nil {
+		query += " WHERE " + q("updated_at") + " >= " + src.dialect.Placeholder(1)
+		args = append(args, src.lastSync)
+	}
+	query += " ORDER BY " + q("updated_at")
+
+	var rows, err = src.db.Query(query, args...)
+	if err != nil {
+		return ConfigError.New(err)
+	}
+	defer rows.Close()
+
+	var values = make(map[string]any)
+	var lastSync = src.lastSync
+	for rows.Next() {
+		var key string
+		var value sql.NullString
+		if err := rows.Scan(&key, &value, &lastSync); err != nil {
+			return ConfigError.New(err)
+		}
+
+		if value.Valid {
+			values[key] = value.String
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return ConfigError.New(err)
+	}
+
+	keys, err := selectSQLKeys(src)
+	if err != nil {
+		return err
+	}
+
+	decrypted, err := c.newDecrypter().decrypt(values)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range decrypted.(map[string]any) {
+		c.set(key, Value{value: value, priority: src.priority, strict: false, source: src.source})
+	}
+
+	for _, key := range c.keysOf(src.source) {
+		if _, ok := keys[key]; !ok {
+			c.unset(key, src.source)
+		}
+	}
+
+	src.lastSync = lastSync
+	return nil
+}
+
+// selectSQLKeys returns all keys of the table with non-NULL values.
+func selectSQLKeys(src *sqlSource) (map[string]struct{}, error) {
+	var q = src.dialect.Quote
+	var rows, err = src.db.Query(
+		"SELECT " + q("key") + " FROM " + q(src.table) + " WHERE " + q("value") + " IS NOT NULL")
+	if err != nil {
+		return nil, ConfigError.New(err)
+	}
+	defer rows.Close()
+
+	var keys = make(map[string]struct{})
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, ConfigError.New(err)
+		}
+		keys[key] = struct{}{}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, ConfigError.New(err)
+	}
+
+	return keys, nil
+}
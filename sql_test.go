@@ -0,0 +1,158 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+func openSettings(t *testing.T) *sql.DB {
+	var db, err = sql.Open("sqlite3", filepath.Join(t.TempDir(), "settings.db"))
+	xycond.ExpectNil(err).Test(t)
+
+	_, err = db.Exec(`CREATE TABLE settings (
+		key TEXT PRIMARY KEY,
+		value TEXT,
+		updated_at INTEGER NOT NULL
+	)`)
+	xycond.ExpectNil(err).Test(t)
+
+	_, err = db.Exec(`INSERT INTO settings VALUES
+		('general.timeout', '3s', 1),
+		('general.retries', '5', 1),
+		('debug', 'true', 2),
+		('unused', NULL, 2)`)
+	xycond.ExpectNil(err).Test(t)
+
+	return db
+}
+
+func TestConfigReadSQL(t *testing.T) {
+	var db = openSettings(t)
+	defer db.Close()
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.ReadSQL(db, "settings", 10, 0)).Test(t)
+
+	xycond.ExpectEqual(cfg.MustGet("general.timeout").MustDuration(), 3*time.Second).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("general.retries").MustInt(), 5).Test(t)
+	xycond.ExpectTrue(cfg.MustGet("debug").MustBool()).Test(t)
+
+	var _, ok = cfg.Get("unused")
+	xycond.ExpectFalse(ok).Test(t)
+
+	// Values are read non-strictly.
+	cfg.Set("general.retries", 1, 10, true)
+	xycond.ExpectEqual(cfg.MustGet("general.retries").MustInt(), 1).Test(t)
+}
+
+func TestConfigReadSQLWithChange(t *testing.T) {
+	var db = openSettings(t)
+	defer db.Close()
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadSQL(db, "settings", 0, 10*time.Millisecond)).Test(t)
+
+	var changes = make(chan string, 10)
+	cfg.AddHook("", func(e xyconfig.Event) { changes <- e.Key })
+
+	_, err := db.Exec(`UPDATE settings SET value = '4s', updated_at = 3
+		WHERE key = 'general.timeout'`)
+	xycond.ExpectNil(err).Test(t)
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("general.timeout").MustDuration() == 4*time.Second
+	})).Test(t)
+
+	_, err = db.Exec(`DELETE FROM settings WHERE key = 'debug'`)
+	xycond.ExpectNil(err).Test(t)
+	xycond.ExpectTrue(eventually(func() bool {
+		var _, ok = cfg.Get("debug")
+		return !ok
+	})).Test(t)
+
+	// Rows updated at the same time as the last read are read again.
+	_, err = db.Exec(`INSERT INTO settings VALUES ('name', 'app', 3)`)
+	xycond.ExpectNil(err).Test(t)
+	xycond.ExpectTrue(eventually(func() bool {
+		var v, ok = cfg.Get("name")
+		return ok && v.MustString() == "app"
+	})).Test(t)
+
+	// Unchanged rows do not fire hooks.
+	time.Sleep(50 * time.Millisecond)
+	xycond.ExpectEqual(len(changes), 3).Test(t)
+}
+
+func TestConfigReadSQLError(t *testing.T) {
+	var db = openSettings(t)
+	defer db.Close()
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	var err = cfg.ReadSQL(db, "settings; DROP TABLE settings", 0, 0)
+	xycond.ExpectError(err, xyconfig.FormatError).Test(t)
+
+	err = cfg.ReadSQL(db, "not_exist", 0, 0)
+	xycond.ExpectError(err, xyconfig.ConfigError).Test(t)
+
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadSQL(db, "not_exist", 0, time.Minute)).Test(t)
+	var status, _ = cfg.Status("sql:not_exist")
+	xycond.ExpectEqual(status.ConsecutiveFailures, 2).Test(t)
+}
+
+func TestConfigReadSQLWithDialect(t *testing.T) {
+	var db = openSettings(t)
+	defer db.Close()
+
+	// SQLite also accepts the quotes and placeholders of these dialects.
+	for i, dialect := range []xyconfig.SQLDialect{
+		xyconfig.SQLiteDialect, xyconfig.PostgreSQLDialect, xyconfig.SQLServerDialect,
+	} {
+		var cfg = xyconfig.GetConfig("")
+		cfg.SetSQLDialect(dialect)
+		xycond.ExpectNil(cfg.ReadSQL(db, "settings", 0, 10*time.Millisecond)).Test(t)
+		xycond.ExpectTrue(cfg.MustGet("debug").MustBool()).Test(t)
+
+		// The placeholders of polls are bound by the dialect.
+		var _, err = db.Exec(`UPDATE settings SET value = ?, updated_at = updated_at + 1
+			WHERE key = 'general.retries'`, i)
+		xycond.ExpectNil(err).Test(t)
+		xycond.ExpectTrue(eventually(func() bool {
+			return cfg.MustGet("general.retries").MustInt() == i
+		})).Test(t)
+		cfg.CloseWatcher()
+	}
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetSQLDialect(xyconfig.SQLDialect{
+		Quote: func(name string) string { return "'" + name },
+	})
+	var err = cfg.ReadSQL(db, "settings", 0, 0)
+	xycond.ExpectError(err, xyconfig.ConfigError).Test(t)
+}
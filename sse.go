@@ -0,0 +1,271 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Events of a SSE source.
+const (
+	// SnapshotEvent contains all values of the source as a JSON object. Values
+	// which are not in the snapshot are removed.
+	SnapshotEvent = "snapshot"
+
+	// ChangeEvent contains a JSON object {"key": "a.b", "value": ...} which
+	// sets the value of a dot-separated key.
+	ChangeEvent = "change"
+
+	// DeleteEvent contains a JSON object {"key": "a.b"} which removes the
+	// dot-separated key and its sub keys.
+	DeleteEvent = "delete"
+)
+
+// maxSSEEventSize is the maximum size of a line of a SSE stream.
+const maxSSEEventSize = 16 << 20
+
+// ReadSSE reads the config values pushed by a Server-Sent Events stream, so
+// changes are applied as soon as they arrive. The stream sends SnapshotEvent,
+// ChangeEvent and DeleteEvent events, all values are read with the priority.
+//
+// The stream is reconnected with an exponential backoff when it is closed or
+// fails. The server should send a snapshot after every connection to resync
+// the values, the id of the last received event is sent in the Last-Event-ID
+// header.
+//
+// It returns after the first snapshot is applied or the first connection
+// fails. As other remote sources, see Status for the health of the stream and
+// SetCacheDir to load the last snapshot if the stream is unreachable. Headers
+// and TLS settings of SetHTTPOptions are applied, but not the timeout. Use
+// UnWatch with the url to close the stream.
+func (c *Config) ReadSSE(url string, priority int) error {
+	var u, err = neturl.Parse(url)
+	if err != nil {
+		return FormatError.Newf("can not parse the url %s (%v)", url, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return FormatError.Newf("unsupported scheme %s", u.Scheme)
+	}
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	var s = &stream{ctx: ctx, cancel: cancel}
+
+	c.lock.Lock()
+	if old, ok := c.timerWatchers[url]; ok {
+		old.Stop()
+	}
+	c.timerWatchers[url] = s
+	c.lock.Unlock()
+
+	var ready = make(chan struct{})
+	var once sync.Once
+	go c.runSSE(url, priority, s, func() { once.Do(func() { close(ready) }) })
+
+	var timer = time.NewTimer(defaultHTTPTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-ready:
+	case <-timer.C:
+	}
+
+	return nil
+}
+
+// runSSE reads the stream and reconnects to it until the stream is stopped.
+// The ready function is called after the first snapshot or failure.
+func (c *Config) runSSE(url string, priority int, s *stream, ready func()) {
+	var lastID string
+	for {
+		var err = c.readSSE(s.ctx, url, priority, &lastID, ready)
+		if s.ctx.Err() != nil {
+			ready()
+			return
+		}
+
+		if err == nil {
+			err = ConfigError.Newf("stream %s is closed", url)
+		}
+
+		c.reportStatus(url, err)
+		c.loadCache(url, priority)
+		ready()
+
+		if !s.wait(c.nextFetch(url, maxRetryDelay)) {
+			return
+		}
+	}
+}
+
+// readSSE connects to the stream and applies its events until the stream is
+// closed.
+func (c *Config) readSSE(
+	ctx context.Context, url string, priority int, lastID *string, ready func(),
+) error {
+	var req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return ConfigError.New(err)
+	}
+
+	c.lock.RLock()
+	var client = &http.Client{}
+	if c.httpClient != nil {
+		var copied = *c.httpClient
+		copied.Timeout = 0
+		client = &copied
+	}
+	for key, values := range c.httpHeader {
+		req.Header[key] = values
+	}
+	c.lock.RUnlock()
+
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if *lastID != "" {
+		req.Header.Set("Last-Event-ID", *lastID)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return ConfigError.New(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ConfigError.Newf("unexpected status %s of %s", resp.Status, url)
+	}
+
+	var scanner = bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxSSEEventSize)
+
+	var event, id string
+	var data []string
+	for scanner.Scan() {
+		var line = scanner.Text()
+		if line == "" {
+			// Events which are buffered when the stream is stopped are not
+			// applied.
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			if len(data) > 0 {
+				var err = c.applySSEEvent(url, priority, event, strings.Join(data, "\n"))
+				if err != nil {
+					logger.Event("sse-error").
+						Field("url", url).Field("event", event).Field("error", err).Warning()
+				} else if event == SnapshotEvent {
+					c.reportStatus(url, nil)
+					ready()
+				}
+			}
+
+			if id != "" {
+				*lastID = id
+			}
+			event, id, data = "", "", nil
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		var field, value, _ = strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			id = value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return ConfigError.New(err)
+	}
+	return nil
+}
+
+// applySSEEvent applies an event of a SSE source. Unknown events are ignored.
+func (c *Config) applySSEEvent(source string, priority int, event, data string) error {
+	switch event {
+	case SnapshotEvent:
+		var flat, _, err = unmarshalFlat(JSON, []byte(data))
+		if err != nil {
+			return err
+		}
+
+		if err := c.readJSON(priority, []byte(data), source); err != nil {
+			return err
+		}
+
+		for _, key := range c.keysOf(source) {
+			if _, ok := flat[key]; !ok {
+				c.unset(key, source)
+			}
+		}
+
+		c.saveCache(source, JSON, []byte(data))
+
+	case ChangeEvent, DeleteEvent:
+		var change struct {
+			Key   string `json:"key"`
+			Value any    `json:"value"`
+		}
+
+		if err := json.Unmarshal([]byte(data), &change); err != nil {
+			return FormatError.Newf("cannot parse %s event (%v)", event, err)
+		}
+
+		if change.Key == "" {
+			return FormatError.Newf("not found key in %s event", event)
+		}
+
+		if event == DeleteEvent {
+			for _, key := range c.keysOf(source) {
+				if key == change.Key || strings.HasPrefix(key, change.Key+".") {
+					c.unset(key, source)
+				}
+			}
+			return nil
+		}
+
+		var value, err = c.newDecrypter().decrypt(change.Value)
+		if err != nil {
+			return err
+		}
+
+		return c.readMap(priority, map[string]any{change.Key: value}, source)
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+// sseSource is a SSE server which sends a snapshot on every connection, then
+// sends the events pushed to it.
+type sseSource struct {
+	lock        sync.Mutex
+	snapshot    string
+	events      chan string
+	connections int32
+	lastID      string
+	closeAfter  bool
+}
+
+func newSSESource(snapshot string) *sseSource {
+	return &sseSource{snapshot: snapshot, events: make(chan string, 10)}
+}
+
+func (s *sseSource) push(event, data string) {
+	s.events <- fmt.Sprintf("event: %s\ndata: %s\n\n", event, data)
+}
+
+func (s *sseSource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var n = atomic.AddInt32(&s.connections, 1)
+
+	s.lock.Lock()
+	s.lastID = r.Header.Get("Last-Event-ID")
+	var snapshot, closeAfter = s.snapshot, s.closeAfter
+	s.lock.Unlock()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	fmt.Fprintf(w, ": comment\nid: %d\nevent: snapshot\ndata: %s\n\n", n, snapshot)
+	w.(http.Flusher).Flush()
+	if closeAfter {
+		return
+	}
+
+	for {
+		select {
+		case event := <-s.events:
+			fmt.Fprint(w, event)
+			w.(http.Flusher).Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func TestConfigReadSSE(t *testing.T) {
+	var source = newSSESource(`{"foo": "bar", "db": {"host": "a", "port": 1}}`)
+	var server = httptest.NewServer(source)
+	defer server.Close()
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+
+	var events int32
+	cfg.AddHook("foo", func(e xyconfig.Event) { atomic.AddInt32(&events, 1) })
+
+	xycond.ExpectNil(cfg.ReadSSE(server.URL, 10)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+	xycond.ExpectEqual(cfg.MustGet("db.host").MustString(), "a").Test(t)
+
+	var status, _ = cfg.Status(server.URL)
+	xycond.ExpectFalse(status.LastSuccess.IsZero()).Test(t)
+
+	source.push(xyconfig.ChangeEvent, `{"key": "foo", "value": "buzz"}`)
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustString() == "buzz"
+	})).Test(t)
+	xycond.ExpectEqual(atomic.LoadInt32(&events), int32(2)).Test(t)
+
+	source.push(xyconfig.ChangeEvent, `{"key": "db.options", "value": {"tls": true}}`)
+	xycond.ExpectTrue(eventually(func() bool {
+		var _, ok = cfg.Get("db.options.tls")
+		return ok
+	})).Test(t)
+
+	source.push(xyconfig.DeleteEvent, `{"key": "db"}`)
+	xycond.ExpectTrue(eventually(func() bool {
+		var _, ok = cfg.Get("db.host")
+		return !ok
+	})).Test(t)
+	var _, ok = cfg.Get("db.options.tls")
+	xycond.ExpectFalse(ok).Test(t)
+
+	// Invalid events are ignored.
+	source.push(xyconfig.ChangeEvent, `{"value": 1}`)
+	source.push("unknown", `{}`)
+	source.push(xyconfig.ChangeEvent, `{"key": "foo", "value": "bizz"}`)
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustString() == "bizz"
+	})).Test(t)
+}
+
+func TestConfigReadSSEReconnect(t *testing.T) {
+	var source = newSSESource(`{"foo": "bar", "buzz": 1}`)
+	source.closeAfter = true
+	var server = httptest.NewServer(source)
+	defer server.Close()
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	xycond.ExpectNil(cfg.ReadSSE(server.URL, 10)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+
+	source.lock.Lock()
+	source.snapshot = `{"foo": "bizz"}`
+	source.lock.Unlock()
+
+	// The values are resynced after reconnecting.
+	xycond.ExpectTrue(eventually(func() bool {
+		return cfg.MustGet("foo").MustString() == "bizz"
+	})).Test(t)
+
+	var _, ok = cfg.Get("buzz")
+	xycond.ExpectFalse(ok).Test(t)
+
+	source.lock.Lock()
+	xycond.ExpectNotEqual(source.lastID, "").Test(t)
+	source.lock.Unlock()
+}
+
+func TestConfigReadSSEUnWatch(t *testing.T) {
+	var source = newSSESource(`{"foo": "bar"}`)
+	var server = httptest.NewServer(source)
+	defer server.Close()
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectNil(cfg.ReadSSE(server.URL, 10)).Test(t)
+	xycond.ExpectNil(cfg.UnWatch(server.URL)).Test(t)
+
+	source.push(xyconfig.ChangeEvent, `{"key": "foo", "value": "buzz"}`)
+	time.Sleep(100 * time.Millisecond)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+	xycond.ExpectEqual(atomic.LoadInt32(&source.connections), int32(1)).Test(t)
+}
+
+func TestConfigReadSSEUnreachable(t *testing.T) {
+	var dir = t.TempDir()
+	var source = newSSESource(`{"foo": "bar"}`)
+	var server = httptest.NewServer(source)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetCacheDir(dir)
+	xycond.ExpectNil(cfg.ReadSSE(server.URL, 10)).Test(t)
+	cfg.CloseWatcher()
+	server.CloseClientConnections()
+	server.Close()
+
+	var other = xyconfig.GetConfig(t.Name() + "-other")
+	defer other.CloseWatcher()
+	other.SetCacheDir(dir)
+	xycond.ExpectNil(other.ReadSSE(server.URL, 10)).Test(t)
+	xycond.ExpectEqual(other.MustGet("foo").MustString(), "bar").Test(t)
+
+	var status, _ = other.Status(server.URL)
+	xycond.ExpectTrue(status.Cached).Test(t)
+	xycond.ExpectEqual(status.ConsecutiveFailures, 1).Test(t)
+}
+
+func TestConfigReadSSEInvalid(t *testing.T) {
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectError(cfg.ReadSSE("ftp://host/events", 0), xyconfig.FormatError).Test(t)
+	xycond.ExpectError(cfg.ReadSSE("http://%zz/events", 0), xyconfig.FormatError).Test(t)
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"math/rand"
+	"time"
+)
+
+// minRetryDelay is the delay before the first retry of a failed fetch. The
+// delay doubles after each consecutive failure, up to the watching interval.
+const minRetryDelay = time.Second
+
+// maxRetryDelay is the maximum delay before reconnecting to a streaming
+// source.
+const maxRetryDelay = 30 * time.Second
+
+// SourceStatus is the health status of a remote source.
+type SourceStatus struct {
+	// LastSuccess is the time of the last successful fetch.
+	LastSuccess time.Time
+
+	// LastFailure is the time of the last failed fetch.
+	LastFailure time.Time
+
+	// LastError is the error of the last failed fetch.
+	LastError error
+
+	// ConsecutiveFailures is the number of failed fetches since the last
+	// successful one.
+	ConsecutiveFailures int
+
+	// Cached is true if the values of the source were loaded from the cache
+	// directory, because the source has not been fetched successfully yet.
+	Cached bool
+}
+
+// Status returns the health status of a remote source, such as a s3 url. The
+// latter returned value is false if the source has not been fetched yet.
+func (c *Config) Status(source string) (SourceStatus, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var status, ok = c.statuses[source]
+	return status, ok
+}
+
+// Statuses returns the health status of all remote sources.
+func (c *Config) Statuses() map[string]SourceStatus {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var statuses = make(map[string]SourceStatus, len(c.statuses))
+	for source, status := range c.statuses {
+		statuses[source] = status
+	}
+	return statuses
+}
+
+// SetErrorHandler sets the function called when fetching a remote source
+// fails, for example to alert that the config is stale.
+func (c *Config) SetErrorHandler(f func(source string, err error)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.errorHandler = f
+}
+
+// reportStatus updates the health status of the source with the result of a
+// fetch. It calls the error handler if err is not nil.
+func (c *Config) reportStatus(source string, err error) {
+	c.lock.Lock()
+	var status = c.statuses[source]
+	if err == nil {
+		status.LastSuccess = time.Now()
+		status.ConsecutiveFailures = 0
+		status.Cached = false
+	} else {
+		status.LastFailure = time.Now()
+		status.LastError = err
+		status.ConsecutiveFailures++
+	}
+	c.statuses[source] = status
+	var handler = c.errorHandler
+	c.lock.Unlock()
+
+	if err == nil {
+		return
+	}
+
+	logger.Event("fetch-error").Field("source", source).
+		Field("failures", status.ConsecutiveFailures).Field("error", err).Warning()
+	if handler != nil {
+		handler(source, err)
+	}
+}
+
+// nextFetch returns the delay before the next fetch of the source. After
+// failures, it is an exponential backoff with jitter which never exceeds d.
+func (c *Config) nextFetch(source string, d time.Duration) time.Duration {
+	c.lock.RLock()
+	var failures = c.statuses[source].ConsecutiveFailures
+	c.lock.RUnlock()
+
+	if failures == 0 {
+		return d
+	}
+
+	var delay = d
+	if failures <= 30 && minRetryDelay<<(failures-1) < d {
+		delay = minRetryDelay << (failures - 1)
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+func TestConfigStatus(t *testing.T) {
+	var client = newFakeS3()
+	client.put("bucket", "app.json", `{"foo": "bar"}`, `"1"`)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetS3Client(client)
+
+	var _, ok = cfg.Status("s3://bucket/app.json")
+	xycond.ExpectFalse(ok).Test(t)
+
+	xycond.ExpectNil(cfg.ReadS3("s3://bucket/app.json", 0)).Test(t)
+	status, ok := cfg.Status("s3://bucket/app.json")
+	xycond.ExpectTrue(ok).Test(t)
+	xycond.ExpectFalse(status.LastSuccess.IsZero()).Test(t)
+	xycond.ExpectTrue(status.LastFailure.IsZero()).Test(t)
+	xycond.ExpectEqual(status.ConsecutiveFailures, 0).Test(t)
+
+	xycond.ExpectNotNil(cfg.ReadS3("s3://bucket/other.json", 0)).Test(t)
+	xycond.ExpectNotNil(cfg.ReadS3("s3://bucket/other.json", 0)).Test(t)
+	status = cfg.Statuses()["s3://bucket/other.json"]
+	xycond.ExpectTrue(status.LastSuccess.IsZero()).Test(t)
+	xycond.ExpectFalse(status.LastFailure.IsZero()).Test(t)
+	xycond.ExpectError(status.LastError, xyconfig.ConfigError).Test(t)
+	xycond.ExpectEqual(status.ConsecutiveFailures, 2).Test(t)
+}
+
+func TestConfigStatusParseError(t *testing.T) {
+	var client = newFakeS3()
+	client.put("bucket", "app.json", `{"foo": "bar"`, `"1"`)
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.SetS3Client(client)
+
+	xycond.ExpectNotNil(cfg.ReadS3("s3://bucket/app.json", 0)).Test(t)
+	var status, _ = cfg.Status("s3://bucket/app.json")
+	xycond.ExpectEqual(status.ConsecutiveFailures, 1).Test(t)
+
+	// The ETag of an invalid object is not recorded, so the object is parsed
+	// again after it is fixed.
+	client.put("bucket", "app.json", `{"foo": "bar"}`, `"1"`)
+	xycond.ExpectNil(cfg.ReadS3("s3://bucket/app.json", 0)).Test(t)
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+}
+
+func TestConfigErrorHandler(t *testing.T) {
+	var client = newFakeS3()
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	cfg.SetS3Client(client)
+
+	var lock sync.Mutex
+	var sources []string
+	cfg.SetErrorHandler(func(source string, err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		sources = append(sources, source)
+	})
+
+	xycond.ExpectNil(cfg.ReadS3("s3://bucket/app.json", 10*time.Millisecond)).Test(t)
+	xycond.ExpectTrue(eventually(func() bool {
+		var status, _ = cfg.Status("s3://bucket/app.json")
+		return status.ConsecutiveFailures >= 3
+	})).Test(t)
+
+	lock.Lock()
+	xycond.ExpectTrue(len(sources) >= 3).Test(t)
+	xycond.ExpectEqual(sources[0], "s3://bucket/app.json").Test(t)
+	lock.Unlock()
+}
+
+func TestConfigRetryWithBackoff(t *testing.T) {
+	var client = newFakeS3()
+
+	var cfg = xyconfig.GetConfig(t.Name())
+	defer cfg.CloseWatcher()
+	cfg.SetS3Client(client)
+
+	// The first retry happens after at most one second, not after the
+	// watching interval.
+	xycond.ExpectNil(cfg.ReadS3("s3://bucket/app.json", time.Hour)).Test(t)
+	client.put("bucket", "app.json", `{"foo": "bar"}`, `"1"`)
+
+	var deadline = time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok := cfg.Get("foo"); ok {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	xycond.ExpectEqual(cfg.MustGet("foo").MustString(), "bar").Test(t)
+	var status, _ = cfg.Status("s3://bucket/app.json")
+	xycond.ExpectEqual(status.ConsecutiveFailures, 0).Test(t)
+}
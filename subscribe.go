@@ -0,0 +1,102 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig
+
+import (
+	"strings"
+
+	"github.com/xybor-x/xylock"
+)
+
+// subscriberLock avoids race condition of subscribers and versions of all
+// Config instances. It is separated from the lock of Config because changes
+// are notified to parent Configs while the lock of a sub-Config is held.
+var subscriberLock = &xylock.RWLock{}
+
+// Subscribe registers a function called for every change of values in the
+// Config and its sub-Configs, including removed values (the New value is
+// empty). Unlike hooks, all subscribed functions are called. The key of the
+// event is the full key, containing the name of the Config.
+//
+// The function is called while the Config is locked, so it must not block or
+// call methods of the Config. It returns a function to unsubscribe.
+func (c *Config) Subscribe(f func(Event)) func() {
+	subscriberLock.Lock()
+	if c.subscribers == nil {
+		c.subscribers = make(map[int]func(Event))
+	}
+	c.nextSubscriber++
+	var id = c.nextSubscriber
+	c.subscribers[id] = f
+	subscriberLock.Unlock()
+
+	return func() {
+		subscriberLock.WLockFunc(func() {
+			delete(c.subscribers, id)
+		})
+	}
+}
+
+// Version returns the number of changes of values in the Config and its
+// sub-Configs. It can be used to detect changes.
+func (c *Config) Version() uint64 {
+	return subscriberLock.RLockFunc(func() any {
+		return c.version
+	}).(uint64)
+}
+
+// notify increases the versions of the Config and its parents, then calls
+// their subscribers with the event.
+func (c *Config) notify(e Event) {
+	var configs = []*Config{c}
+	var name = c.name
+	for {
+		var i = strings.LastIndex(name, ".")
+		if i < 0 {
+			break
+		}
+
+		name = name[:i]
+		var parent = globalLock.RLockFunc(func() any {
+			return configMap[name]
+		}).(*Config)
+
+		if parent != nil {
+			configs = append(configs, parent)
+		}
+	}
+
+	var subscribers []func(Event)
+	subscriberLock.WLockFunc(func() {
+		for _, cfg := range configs {
+			cfg.version++
+			for _, f := range cfg.subscribers {
+				subscribers = append(subscribers, f)
+			}
+		}
+	})
+
+	e.New.sensitive = isSensitive(e.Key)
+	e.Old.sensitive = e.New.sensitive
+	for _, f := range subscribers {
+		f(e)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2022 xybor-x
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package xyconfig_test
+
+import (
+	"testing"
+
+	"github.com/xybor-x/xycond"
+	"github.com/xybor-x/xyconfig"
+)
+
+func TestConfigSubscribe(t *testing.T) {
+	var cfg = xyconfig.GetConfig(t.Name())
+	cfg.AddHook("db", func(e xyconfig.Event) {})
+
+	var keys []string
+	var unsubscribe = cfg.Subscribe(func(e xyconfig.Event) {
+		keys = append(keys, e.Key)
+	})
+
+	xycond.ExpectNil(cfg.ReadJSON(0, []byte(`{"db": {"host": "a"}}`))).Test(t)
+	cfg.Set("db.port", 1, 0, true)
+	cfg.Set("db.port", 1, 0, true)
+	xyconfig.GetConfig(t.Name()+".db").Set("user", "admin", 0, true)
+
+	xycond.ExpectEqual(len(keys), 3).Test(t)
+	xycond.ExpectIn(t.Name()+".db.host", keys).Test(t)
+	xycond.ExpectIn(t.Name()+".db.port", keys).Test(t)
+	xycond.ExpectIn(t.Name()+".db.user", keys).Test(t)
+
+	unsubscribe()
+	cfg.Set("db.port", 2, 0, true)
+	xycond.ExpectEqual(len(keys), 3).Test(t)
+}
+
+func TestConfigVersion(t *testing.T) {
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectEqual(cfg.Version(), uint64(0)).Test(t)
+
+	cfg.Set("a.b", 1, 0, true)
+	cfg.Set("a.b", 1, 0, true)
+	cfg.Set("c", 1, 0, true)
+	xycond.ExpectEqual(cfg.Version(), uint64(2)).Test(t)
+	xycond.ExpectEqual(xyconfig.GetConfig(t.Name()+".a").Version(), uint64(1)).Test(t)
+}
+
+func TestConfigName(t *testing.T) {
+	var cfg = xyconfig.GetConfig(t.Name())
+	xycond.ExpectEqual(cfg.Name(), t.Name()).Test(t)
+}
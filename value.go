@@ -29,9 +29,18 @@ import (
 
 // Value instance represents for values in Config.
 type Value struct {
-	priority int
-	value    any
-	strict   bool
+	priority  int
+	value     any
+	strict    bool
+	sensitive bool
+
+	// source is where the value was read from, such as a file path, an url,
+	// or "env". It is empty for values which are set directly.
+	source string
+
+	// ciphertext is the encrypted form of the value as it was read. It is nil
+	// if the value was not encrypted.
+	ciphertext any
 }
 
 // IsNil return true if value is nil.
@@ -224,7 +233,7 @@ func (v Value) AsArray() ([]Value, bool) {
 		var elements []Value
 		for _, e := range strings.Split(s, ",") {
 			e = strings.Trim(e, " ")
-			elements = append(elements, Value{value: e, strict: false})
+			elements = append(elements, Value{value: e, strict: false, sensitive: v.sensitive})
 		}
 
 		return elements, true
@@ -233,7 +242,7 @@ func (v Value) AsArray() ([]Value, bool) {
 	if a, ok := v.value.([]any); ok {
 		var elements []Value
 		for _, e := range a {
-			elements = append(elements, Value{value: e, strict: true})
+			elements = append(elements, Value{value: e, strict: true, sensitive: v.sensitive})
 		}
 		return elements, true
 	}
@@ -250,7 +259,21 @@ func (v Value) MustArray() []Value {
 	return a
 }
 
-// String returns the string representation of Value.
+// IsSensitive returns true if the value belongs to a sensitive key.
+func (v Value) IsSensitive() bool {
+	return v.sensitive
+}
+
+// String returns the string representation of Value. The value of a sensitive
+// key is redacted.
 func (v Value) String() string {
+	if v.sensitive {
+		return redacted
+	}
+
+	if c, ok := v.AsConfig(); ok {
+		return fmt.Sprint(c.ToMap())
+	}
+
 	return fmt.Sprint(v.value)
 }